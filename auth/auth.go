@@ -0,0 +1,202 @@
+// Package auth authenticates the server's consumers with API keys. Keys are listed in a YAML file
+// holding only their SHA-256 hashes, along with each key's quota and the routes it may request:
+//
+//	keys:
+//	  - id: dashboard
+//	    # echo -n "<key>" | sha256sum
+//	    hash: sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//	    quota:
+//	      requests: 10000
+//	      period: 24h
+//	    routes:
+//	      - /api/asset
+//	      - /api/asset/*
+//	  - id: ops
+//	    hash: sha256:60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752
+//	    admin: true
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/ultd/messari-server/config"
+	"gopkg.in/yaml.v2"
+)
+
+// hashPrefix is the prefix of the keys' hashes in the key file, naming the hash function used
+const hashPrefix = "sha256:"
+
+// Key struct is an API key of the key file
+type Key struct {
+	// ID names the key in logs and usage reports, it's not a secret
+	ID   string `yaml:"id"`
+	Hash string `yaml:"hash"`
+	// Quota limits how many requests the key can make, it's unlimited when empty
+	Quota Quota `yaml:"quota"`
+	// Routes are the route patterns (ie. "/api/asset/:symbolOrSlug") the key may request, a pattern
+	// ending with * matches every route starting with it, so "/api/asset*" matches "/api/assets/batch"
+	// too while "/api/asset/*" doesn't. The key may request any route when empty.
+	Routes []string `yaml:"routes"`
+	// Admin keys may request the admin routes (ie. the usage of every key)
+	Admin bool `yaml:"admin"`
+}
+
+// Quota struct is a number of requests allowed per period
+type Quota struct {
+	Requests int64         `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+}
+
+// Allows func returns whether the key may request route
+func (k *Key) Allows(route string) bool {
+	if len(k.Routes) == 0 {
+		return true
+	}
+	for _, pattern := range k.Routes {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(route, prefix) {
+				return true
+			}
+			continue
+		}
+		if route == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash func returns the hash of raw as it's written in the key file
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// keyFile struct is the content of the key file
+type keyFile struct {
+	Keys []Key `yaml:"keys"`
+}
+
+// load func reads and validates the keys of the key file at path, returning them by hash
+func load(path string) (map[string]*Key, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read keys file: %w", err)
+	}
+	var f keyFile
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("could not parse keys file %s: %w", path, err)
+	}
+
+	keys := make(map[string]*Key, len(f.Keys))
+	ids := make(map[string]bool, len(f.Keys))
+	for i := range f.Keys {
+		k := &f.Keys[i]
+		if k.ID == "" {
+			return nil, fmt.Errorf("key #%d in %s has no id", i+1, path)
+		}
+		if ids[k.ID] {
+			return nil, fmt.Errorf("key %s is listed more than once in %s", k.ID, path)
+		}
+		ids[k.ID] = true
+
+		k.Hash = strings.ToLower(k.Hash)
+		digest := strings.TrimPrefix(k.Hash, hashPrefix)
+		if b, err := hex.DecodeString(digest); err != nil || digest == k.Hash || len(b) != sha256.Size {
+			return nil, fmt.Errorf("hash of key %s in %s is not sha256:<hex digest>", k.ID, path)
+		}
+		if _, ok := keys[k.Hash]; ok {
+			return nil, fmt.Errorf("key %s in %s has the same hash as another key", k.ID, path)
+		}
+		if k.Quota.Requests < 0 {
+			return nil, fmt.Errorf("quota of key %s in %s can't be negative", k.ID, path)
+		}
+		if k.Quota.Requests > 0 && k.Quota.Period <= 0 {
+			return nil, fmt.Errorf("quota of key %s in %s needs a positive period (ie. 24h)", k.ID, path)
+		}
+		for _, route := range k.Routes {
+			if !strings.HasPrefix(route, "/") {
+				return nil, fmt.Errorf("route %q of key %s in %s must start with /", route, k.ID, path)
+			}
+		}
+		keys[k.Hash] = k
+	}
+	return keys, nil
+}
+
+// Store struct holds the keys of a key file and how much each of them has been used. Usage is kept
+// in memory only, so it starts over when the server restarts.
+type Store struct {
+	path string
+
+	mu    sync.RWMutex
+	keys  map[string]*Key
+	usage map[string]*usage
+}
+
+// Load func returns a Store of the keys in the key file at path
+func Load(path string) (*Store, error) {
+	keys, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, usage: map[string]*usage{}}
+	s.replace(keys)
+	return s, nil
+}
+
+// replace func swaps the Store's keys for keys, keeping the usage of the keys which are still there
+func (s *Store) replace(keys map[string]*Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	usages := make(map[string]*usage, len(keys))
+	for _, k := range keys {
+		u, ok := s.usage[k.ID]
+		if !ok {
+			u = &usage{routes: map[string]int64{}}
+		}
+		usages[k.ID] = u
+	}
+	s.usage = usages
+}
+
+// Authenticate func returns the Key whose hash is raw's, false if there's none
+func (s *Store) Authenticate(raw string) (*Key, bool) {
+	if raw == "" {
+		return nil, false
+	}
+	h := Hash(raw)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[h]
+	return k, ok
+}
+
+// Reload func loads the keys again from the key file, the current keys are kept if it's invalid
+func (s *Store) Reload() error {
+	keys, err := load(s.path)
+	if err != nil {
+		return err
+	}
+	s.replace(keys)
+	logrus.Infof("loaded %d API keys from %s", len(keys), s.path)
+	return nil
+}
+
+// Watch func reloads the keys whenever the key file changes until ctx is done
+func (s *Store) Watch(ctx context.Context) error {
+	return config.WatchFile(ctx, s.path, func() {
+		if err := s.Reload(); err != nil {
+			logrus.Errorf("could not reload API keys, keeping the current ones: %v", err)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeKeys func writes a key file holding keys and returns its path
+func writeKeys(t *testing.T, keys string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	if err := ioutil.WriteFile(path, []byte(keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	hash := Hash("some-key")
+	other := Hash("other-key")
+	tests := []struct {
+		name string
+		keys string
+		// err is part of the error Load returns, "" when it succeeds
+		err string
+	}{
+		{name: "valid", keys: "keys:\n  - id: a\n    hash: " + hash + "\n    quota: {requests: 10, period: 1h}\n    routes: [/api/asset]\n  - id: b\n    hash: " + strings.ToUpper(other)[:7] + other[7:]},
+		{name: "no keys", keys: "keys: []"},
+		{name: "unknown field", keys: "keys:\n  - id: a\n    hash: " + hash + "\n    secret: x", err: "could not parse"},
+		{name: "missing id", keys: "keys:\n  - hash: " + hash, err: "has no id"},
+		{name: "repeated id", keys: "keys:\n  - id: a\n    hash: " + hash + "\n  - id: a\n    hash: " + other, err: "more than once"},
+		{name: "repeated hash", keys: "keys:\n  - id: a\n    hash: " + hash + "\n  - id: b\n    hash: " + hash, err: "same hash"},
+		{name: "no prefix", keys: "keys:\n  - id: a\n    hash: " + strings.TrimPrefix(hash, "sha256:"), err: "not sha256:"},
+		{name: "other function", keys: "keys:\n  - id: a\n    hash: md5:" + strings.TrimPrefix(hash, "sha256:"), err: "not sha256:"},
+		{name: "not hex", keys: "keys:\n  - id: a\n    hash: sha256:" + strings.Repeat("z", 64), err: "not sha256:"},
+		{name: "short digest", keys: "keys:\n  - id: a\n    hash: " + hash[:len(hash)-2], err: "not sha256:"},
+		{name: "negative quota", keys: "keys:\n  - id: a\n    hash: " + hash + "\n    quota: {requests: -1}", err: "can't be negative"},
+		{name: "quota without period", keys: "keys:\n  - id: a\n    hash: " + hash + "\n    quota: {requests: 5}", err: "positive period"},
+		{name: "relative route", keys: "keys:\n  - id: a\n    hash: " + hash + "\n    routes: [api/asset]", err: "must start with /"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeKeys(t, tt.keys))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want one about %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	// hashes aren't case sensitive
+	s, err := Load(writeKeys(t, "keys:\n  - id: a\n    hash: "+strings.ToUpper(Hash("some-key"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, ok := s.Authenticate("some-key"); !ok || k.ID != "a" {
+		t.Errorf("got %v and %v for the key, want key a", k, ok)
+	}
+	for _, raw := range []string{"", "other-key", "some-key ", Hash("some-key")} {
+		if k, ok := s.Authenticate(raw); ok {
+			t.Errorf("got key %s for %q", k.ID, raw)
+		}
+	}
+}
+
+func TestKeyAllows(t *testing.T) {
+	tests := []struct {
+		routes []string
+		route  string
+		want   bool
+	}{
+		{routes: nil, route: "/api/aggregate", want: true},
+		{routes: []string{"/api/asset"}, route: "/api/asset", want: true},
+		{routes: []string{"/api/asset"}, route: "/api/asset/:symbolOrSlug", want: false},
+		{routes: []string{"/api/asset/*"}, route: "/api/asset/:symbolOrSlug", want: true},
+		{routes: []string{"/api/asset/*"}, route: "/api/asset", want: false},
+		// * matches any route starting with what's before it, even in the middle of a segment
+		{routes: []string{"/api/asset*"}, route: "/api/asset", want: true},
+		{routes: []string{"/api/asset*"}, route: "/api/assets/batch", want: true},
+		{routes: []string{"/api/*"}, route: "/admin/usage", want: false},
+		{routes: []string{"/*"}, route: "/admin/usage", want: true},
+		{routes: []string{"/api/search", "/admin/*"}, route: "/admin/usage/:id", want: true},
+		{routes: []string{"/api/search"}, route: "", want: false},
+	}
+	for _, tt := range tests {
+		k := &Key{ID: "a", Routes: tt.routes}
+		if got := k.Allows(tt.route); got != tt.want {
+			t.Errorf("key with routes %v allows %q: got %v, want %v", tt.routes, tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestStoreUse(t *testing.T) {
+	s, err := Load(writeKeys(t, "keys:\n  - id: a\n    hash: "+Hash("some-key")+"\n    quota: {requests: 2, period: 1m}\n  - id: b\n    hash: "+Hash("other-key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Authenticate("some-key")
+	b, _ := s.Authenticate("other-key")
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	use := func(k *Key, at time.Duration, wantOK bool, wantRetry time.Duration) {
+		t.Helper()
+		ok, retry := s.Use(k, "/api/search", start.Add(at))
+		if ok != wantOK || retry != wantRetry {
+			t.Fatalf("use of %s at +%s: got %v and retry after %s, want %v and %s", k.ID, at, ok, retry, wantOK, wantRetry)
+		}
+	}
+	use(a, 0, true, 0)
+	use(a, 10*time.Second, true, 0)
+	use(a, 59*time.Second, false, time.Second)
+	use(a, time.Minute-time.Nanosecond, false, time.Nanosecond)
+	// the window starts over once its period is over
+	use(a, time.Minute, true, 0)
+	use(a, time.Minute+time.Second, true, 0)
+	use(a, 2*time.Minute-time.Nanosecond, false, time.Nanosecond)
+	// a key without a quota is never refused
+	for i := 0; i < 100; i++ {
+		use(b, 0, true, 0)
+	}
+
+	u, ok := s.UsageOf("a", start.Add(time.Minute+2*time.Second))
+	if !ok {
+		t.Fatal("no usage of key a")
+	}
+	if u.Requests != 4 || u.Rejected != 3 || u.Routes["/api/search"] != 4 {
+		t.Errorf("got %d requests, %d rejected and routes %v, want 4, 3 and 4 to /api/search", u.Requests, u.Rejected, u.Routes)
+	}
+	if u.Quota == nil || u.Quota.Remaining != 0 || !u.Quota.WindowStart.Equal(start.Add(time.Minute)) {
+		t.Errorf("got quota %+v, want none remaining in the window started at +1m", u.Quota)
+	}
+	if u, _ := s.UsageOf("a", start.Add(2*time.Minute)); u.Quota.Remaining != 2 || u.Quota.WindowStart != nil {
+		t.Errorf("got quota %+v once the window is over, want all of it remaining", u.Quota)
+	}
+}
+
+func TestStoreReloadKeepsUsage(t *testing.T) {
+	path := writeKeys(t, "keys:\n  - id: a\n    hash: "+Hash("some-key")+"\n    quota: {requests: 3, period: 1h}\n  - id: b\n    hash: "+Hash("other-key"))
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	a, _ := s.Authenticate("some-key")
+	b, _ := s.Authenticate("other-key")
+	s.Use(a, "/api/search", now)
+	s.Use(a, "/api/search", now)
+	s.Use(b, "/api/search", now)
+
+	// key a's quota is lowered and key b is removed
+	if err := ioutil.WriteFile(path, []byte("keys:\n  - id: a\n    hash: "+Hash("some-key")+"\n    quota: {requests: 1, period: 1h}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Authenticate("other-key"); ok {
+		t.Error("removed key b is still authenticated")
+	}
+	if _, ok := s.UsageOf("b", now); ok {
+		t.Error("removed key b still has a usage")
+	}
+	a, _ = s.Authenticate("some-key")
+	u, _ := s.UsageOf("a", now)
+	if u.Requests != 2 || u.Quota.Remaining != 0 {
+		t.Errorf("got %d requests and %d remaining after the reload, want 2 and 0", u.Requests, u.Quota.Remaining)
+	}
+	if ok, _ := s.Use(a, "/api/search", now); ok {
+		t.Error("key a was let through past its lowered quota")
+	}
+
+	// an invalid key file keeps the current keys
+	if err := ioutil.WriteFile(path, []byte("keys:\n  - id: a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err == nil {
+		t.Fatal("got no error reloading an invalid key file")
+	}
+	if _, ok := s.Authenticate("some-key"); !ok {
+		t.Error("key a was dropped by an invalid reload")
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"sort"
+	"time"
+)
+
+// usage struct counts the requests made with a key
+type usage struct {
+	requests int64
+	rejected int64
+	routes   map[string]int64
+	lastUsed time.Time
+	// windowStart is when the key's current quota period started, windowRequests how many requests
+	// it made since
+	windowStart    time.Time
+	windowRequests int64
+}
+
+// Usage struct reports how much a key has been used since the server started
+type Usage struct {
+	ID string `json:"id"`
+	// Requests counts the requests let through, Rejected those refused for exceeding the quota
+	Requests int64            `json:"requests"`
+	Rejected int64            `json:"rejected"`
+	Routes   map[string]int64 `json:"routes"`
+	LastUsed *time.Time       `json:"lastUsed"`
+	Quota    *QuotaUsage      `json:"quota"`
+}
+
+// QuotaUsage struct reports how much of a key's quota is used in its current period
+type QuotaUsage struct {
+	Requests    int64      `json:"requests"`
+	Period      string     `json:"period"`
+	Remaining   int64      `json:"remaining"`
+	WindowStart *time.Time `json:"windowStart"`
+}
+
+// Use func records a request to route made with k at now. It returns false and how long until the
+// quota allows requests again when k has used up its quota.
+func (s *Store) Use(k *Key, route string, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.usage[k.ID]
+	if !ok {
+		// the key was removed by a reload since it was authenticated
+		u = &usage{routes: map[string]int64{}}
+	}
+	u.lastUsed = now
+
+	if q := k.Quota; q.Requests > 0 {
+		if now.Sub(u.windowStart) >= q.Period {
+			u.windowStart = now
+			u.windowRequests = 0
+		}
+		if u.windowRequests >= q.Requests {
+			u.rejected++
+			return false, u.windowStart.Add(q.Period).Sub(now)
+		}
+		u.windowRequests++
+	}
+	u.requests++
+	u.routes[route]++
+	return true, 0
+}
+
+// Usage func returns the Usage of every key, sorted by ID
+func (s *Store) Usage(now time.Time) []Usage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	usages := make([]Usage, 0, len(s.keys))
+	for _, k := range s.keys {
+		usages = append(usages, s.usageOf(k, now))
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].ID < usages[j].ID })
+	return usages
+}
+
+// UsageOf func returns the Usage of the key with id, false if there's no such key
+func (s *Store) UsageOf(id string, now time.Time) (Usage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.ID == id {
+			return s.usageOf(k, now), true
+		}
+	}
+	return Usage{}, false
+}
+
+// usageOf func returns the Usage of k, s.mu must be held
+func (s *Store) usageOf(k *Key, now time.Time) Usage {
+	u := s.usage[k.ID]
+	report := Usage{
+		ID:       k.ID,
+		Requests: u.requests,
+		Rejected: u.rejected,
+		Routes:   make(map[string]int64, len(u.routes)),
+	}
+	for route, n := range u.routes {
+		report.Routes[route] = n
+	}
+	if !u.lastUsed.IsZero() {
+		lastUsed := u.lastUsed
+		report.LastUsed = &lastUsed
+	}
+	if q := k.Quota; q.Requests > 0 {
+		quota := &QuotaUsage{Requests: q.Requests, Period: q.Period.String(), Remaining: q.Requests}
+		if now.Sub(u.windowStart) < q.Period {
+			windowStart := u.windowStart
+			quota.WindowStart = &windowStart
+			// the quota may have been lowered by a reload since the requests were made
+			if quota.Remaining -= u.windowRequests; quota.Remaining < 0 {
+				quota.Remaining = 0
+			}
+		}
+		report.Quota = quota
+	}
+	return report
+}
@@ -0,0 +1,535 @@
+// Package config loads the server's configuration from a YAML file, environment variables and command
+// line flags. Each source overrides the one before it, so the precedence is:
+//
+//	defaults < config file < environment variables < flags
+//
+// The config file is given by the -config flag or the CONFIG_FILE environment variable, and is optional.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Config struct is the server's configuration. Durations in the config file are written as
+// Go durations (ie. "5m", "30s").
+type Config struct {
+	// Listen is the address the HTTP server listens on (ie. ":8000")
+	Listen string `yaml:"listen"`
+	// ShutdownTimeout is how long in-flight requests are given to finish when the server is stopped
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	Log             Log           `yaml:"log"`
+	Messari         Messari       `yaml:"messari"`
+	Cache           Cache         `yaml:"cache"`
+	// RatesFile is the path of the fiat exchange rates file of the quote package, quoting in fiat
+	// currencies is disabled when it's empty
+	RatesFile string    `yaml:"rates_file"`
+	Features  Features  `yaml:"features"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Auth      Auth      `yaml:"auth"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+
+	// path is the config file the Config was loaded from, if any
+	path string
+}
+
+// Tracing struct configures exporting OpenTelemetry traces
+type Tracing struct {
+	// Exporter is "none", "otlp" to export to an OpenTelemetry collector over HTTP or "stdout" for local debugging
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the collector's host and port (ie. "otel-collector:4318") when exporting with otlp
+	Endpoint string `yaml:"endpoint"`
+	// Insecure exports to the collector over plain HTTP instead of HTTPS
+	Insecure bool `yaml:"insecure"`
+	// SampleRatio is the fraction of traces started by this server which are sampled, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio"`
+	ServiceName string  `yaml:"service_name"`
+}
+
+// Auth struct configures authenticating the server's consumers with API keys
+type Auth struct {
+	// KeysFile is the path of the auth package's key file, every request is let through when it's empty
+	KeysFile string `yaml:"keys_file"`
+}
+
+// RateLimit struct configures limiting the requests of each client of the server, an API key or
+// an IP when requests aren't authenticated. Limits are in cost units, a request costs 1 unless its
+// route is listed in Costs.
+type RateLimit struct {
+	// RequestsPerMinute and Burst limit each client's requests, they aren't limited when it's 0
+	RequestsPerMinute float64 `yaml:"requests_per_minute"`
+	Burst             int     `yaml:"burst"`
+	// Costs are the costs of expensive routes by route pattern (ie. "/api/aggregate")
+	Costs map[string]int `yaml:"costs"`
+	// MaxExpensive is how many requests to routes costing more than 1 are handled at the same time
+	// across all clients, they aren't capped when it's 0
+	MaxExpensive int `yaml:"max_expensive"`
+	// TrustedProxies are the IPs or CIDRs (ie. "10.0.0.0/8") of the reverse proxies in front of the server.
+	// The X-Forwarded-For header is only used to tell clients apart when they connect through one of them.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// CostOf func returns the cost of a request to route
+func (r RateLimit) CostOf(route string) int {
+	if cost, ok := r.Costs[route]; ok {
+		return cost
+	}
+	return 1
+}
+
+// TrustsProxy func returns whether ip is one of TrustedProxies, it's only valid to call on a validated Config
+func (r RateLimit) TrustsProxy(ip net.IP) bool {
+	for _, proxy := range r.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if net.ParseIP(proxy).Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Features struct toggles optional routes on and off, a disabled route responds with 404
+type Features struct {
+	Batch         bool `yaml:"batch"`
+	ProfileSearch bool `yaml:"profile_search"`
+	Screener      bool `yaml:"screener"`
+	Movers        bool `yaml:"movers"`
+}
+
+// Log struct configures logging
+type Log struct {
+	// Level is one of logrus' levels (ie. "debug", "info", "warn")
+	Level string `yaml:"level"`
+	// Format is either "text" or "json"
+	Format string `yaml:"format"`
+}
+
+// Messari struct configures the client of Messari's API
+type Messari struct {
+	APIKey string `yaml:"api_key" redact:"true"`
+	// APIKeys are more API keys to spread requests across along with APIKey
+	APIKeys []string      `yaml:"api_keys" redact:"true"`
+	BaseURL string        `yaml:"base_url"`
+	Timeout time.Duration `yaml:"timeout"`
+	// RequestsPerMinute and Burst limit the requests made with each API key against Messari's API
+	RequestsPerMinute float64 `yaml:"requests_per_minute"`
+	Burst             int     `yaml:"burst"`
+	// Concurrency is how many requests batch calls make at the same time
+	Concurrency int         `yaml:"concurrency"`
+	Passthrough Passthrough `yaml:"passthrough"`
+}
+
+// Keys func returns APIKey and APIKeys, without empty and repeated keys
+func (m Messari) Keys() []string {
+	keys := make([]string, 0, len(m.APIKeys)+1)
+	seen := make(map[string]bool, len(m.APIKeys)+1)
+	for _, key := range append([]string{m.APIKey}, m.APIKeys...) {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Passthrough struct configures letting requests carry their caller's own Messari API key in the
+// X-Messari-Api-Key header, which is then used instead of APIKey for the calls they make. Only the
+// routes calling Messari for each request accept the header, the cached data other routes answer from
+// is fetched with the server's own keys and isn't accounted per caller.
+type Passthrough struct {
+	Enabled bool `yaml:"enabled"`
+	// RequestsPerMinute and Burst limit the requests made with each caller's key
+	RequestsPerMinute float64 `yaml:"requests_per_minute"`
+	Burst             int     `yaml:"burst"`
+}
+
+// Cache struct holds how long the locally kept copy of Messari's asset universe is used before it's
+// refreshed
+type Cache struct {
+	// DirectoryTTL and ProfilesTTL are no longer used since the asset directory and the profile index are
+	// rebuilt on each refresh of the snapshot, they're only still parsed so existing config files load
+	DirectoryTTL time.Duration `yaml:"directory_ttl"`
+	ProfilesTTL  time.Duration `yaml:"profiles_ttl"`
+	SnapshotTTL  time.Duration `yaml:"snapshot_ttl"`
+}
+
+// Default func returns the Config used for anything which isn't configured
+func Default() *Config {
+	return &Config{
+		Listen:          ":8000",
+		ShutdownTimeout: 30 * time.Second,
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Messari: Messari{
+			BaseURL: "https://data.messari.io",
+			Timeout: 30 * time.Second,
+			// Messari allows 30 requests per minute with an API key
+			RequestsPerMinute: 30,
+			Burst:             5,
+			Concurrency:       4,
+			Passthrough: Passthrough{
+				RequestsPerMinute: 30,
+				Burst:             5,
+			},
+		},
+		Cache: Cache{
+			DirectoryTTL: time.Hour,
+			ProfilesTTL:  6 * time.Hour,
+			SnapshotTTL:  5 * time.Minute,
+		},
+		RateLimit: RateLimit{
+			RequestsPerMinute: 120,
+			Burst:             20,
+			// routes going through the whole asset universe cost more than those about a single asset
+			Costs: map[string]int{
+				"/api/asset":               5,
+				"/api/assets/batch":        5,
+				"/api/aggregate":           10,
+				"/api/aggregate/breakdown": 10,
+				"/api/screener":            5,
+				"/api/movers":              5,
+			},
+			MaxExpensive: 8,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "messari-server",
+		},
+		Features: Features{
+			Batch:         true,
+			ProfileSearch: true,
+			Screener:      true,
+			Movers:        true,
+		},
+	}
+}
+
+// setting is a single configurable value, settable by an environment variable and a flag
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"LISTEN_ADDR", "listen", "address the server listens on", func(c *Config, v string) error {
+		c.Listen = v
+		return nil
+	}},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long in-flight requests are given to finish on shutdown (ie. 30s)", func(c *Config, v string) error {
+		return parseDuration(v, &c.ShutdownTimeout)
+	}},
+	{"LOG_LEVEL", "log-level", "log level (debug, info, warn, error)", func(c *Config, v string) error {
+		c.Log.Level = v
+		return nil
+	}},
+	{"LOG_FORMAT", "log-format", "log format (text or json)", func(c *Config, v string) error {
+		c.Log.Format = v
+		return nil
+	}},
+	{"MESSARI_API_KEY", "messari-api-key", "Messari API key", func(c *Config, v string) error {
+		c.Messari.APIKey = v
+		return nil
+	}},
+	{"MESSARI_API_KEYS", "messari-api-keys", "more Messari API keys to spread requests across, separated by commas", func(c *Config, v string) error {
+		c.Messari.APIKeys = nil
+		for _, key := range strings.Split(v, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				c.Messari.APIKeys = append(c.Messari.APIKeys, key)
+			}
+		}
+		return nil
+	}},
+	{"MESSARI_BASE_URL", "messari-base-url", "base URL of Messari's API", func(c *Config, v string) error {
+		c.Messari.BaseURL = v
+		return nil
+	}},
+	{"MESSARI_TIMEOUT", "messari-timeout", "timeout of a request against Messari's API (ie. 30s)", func(c *Config, v string) error {
+		return parseDuration(v, &c.Messari.Timeout)
+	}},
+	{"MESSARI_REQUESTS_PER_MINUTE", "messari-requests-per-minute", "requests per minute allowed against Messari's API", func(c *Config, v string) error {
+		return parseFloat(v, &c.Messari.RequestsPerMinute)
+	}},
+	{"MESSARI_BURST", "messari-burst", "requests allowed in a burst against Messari's API", func(c *Config, v string) error {
+		return parseInt(v, &c.Messari.Burst)
+	}},
+	{"MESSARI_CONCURRENCY", "messari-concurrency", "requests batch calls make at the same time", func(c *Config, v string) error {
+		return parseInt(v, &c.Messari.Concurrency)
+	}},
+	{"MESSARI_PASSTHROUGH", "messari-passthrough", "let requests carry their own Messari API key in the X-Messari-Api-Key header", func(c *Config, v string) error {
+		return parseBool(v, &c.Messari.Passthrough.Enabled)
+	}},
+	{"MESSARI_PASSTHROUGH_REQUESTS_PER_MINUTE", "messari-passthrough-requests-per-minute", "requests per minute made with each caller's own Messari API key", func(c *Config, v string) error {
+		return parseFloat(v, &c.Messari.Passthrough.RequestsPerMinute)
+	}},
+	{"MESSARI_PASSTHROUGH_BURST", "messari-passthrough-burst", "requests made at once with each caller's own Messari API key", func(c *Config, v string) error {
+		return parseInt(v, &c.Messari.Passthrough.Burst)
+	}},
+	{"DIRECTORY_TTL", "directory-ttl", "no longer used, the asset directory is rebuilt with the snapshot", func(c *Config, v string) error {
+		return parseDuration(v, &c.Cache.DirectoryTTL)
+	}},
+	{"PROFILES_TTL", "profiles-ttl", "no longer used, the profile index is rebuilt with the snapshot", func(c *Config, v string) error {
+		return parseDuration(v, &c.Cache.ProfilesTTL)
+	}},
+	{"SNAPSHOT_REFRESH_INTERVAL", "snapshot-ttl", "how often the asset snapshot is refreshed (ie. 5m)", func(c *Config, v string) error {
+		return parseDuration(v, &c.Cache.SnapshotTTL)
+	}},
+	{"FX_RATES_FILE", "rates-file", "path of the fiat exchange rates file", func(c *Config, v string) error {
+		c.RatesFile = v
+		return nil
+	}},
+	{"TRACING_EXPORTER", "tracing-exporter", "where traces are exported to (none, otlp or stdout)", func(c *Config, v string) error {
+		c.Tracing.Exporter = v
+		return nil
+	}},
+	{"OTLP_ENDPOINT", "otlp-endpoint", "host:port of the OpenTelemetry collector traces are exported to", func(c *Config, v string) error {
+		c.Tracing.Endpoint = v
+		return nil
+	}},
+	{"OTLP_INSECURE", "otlp-insecure", "export traces over plain HTTP", func(c *Config, v string) error {
+		return parseBool(v, &c.Tracing.Insecure)
+	}},
+	{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of traces which are sampled, from 0 to 1", func(c *Config, v string) error {
+		return parseFloat(v, &c.Tracing.SampleRatio)
+	}},
+	{"RATE_LIMIT_REQUESTS_PER_MINUTE", "rate-limit-requests-per-minute", "cost units each client can use per minute, 0 to not limit clients", func(c *Config, v string) error {
+		return parseFloat(v, &c.RateLimit.RequestsPerMinute)
+	}},
+	{"RATE_LIMIT_BURST", "rate-limit-burst", "cost units each client can use at once", func(c *Config, v string) error {
+		return parseInt(v, &c.RateLimit.Burst)
+	}},
+	{"RATE_LIMIT_MAX_EXPENSIVE", "rate-limit-max-expensive", "expensive requests handled at the same time, 0 to not cap them", func(c *Config, v string) error {
+		return parseInt(v, &c.RateLimit.MaxExpensive)
+	}},
+	{"RATE_LIMIT_TRUSTED_PROXIES", "rate-limit-trusted-proxies", "IPs or CIDRs of the reverse proxies whose X-Forwarded-For is trusted, separated by commas", func(c *Config, v string) error {
+		c.RateLimit.TrustedProxies = nil
+		for _, proxy := range strings.Split(v, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				c.RateLimit.TrustedProxies = append(c.RateLimit.TrustedProxies, proxy)
+			}
+		}
+		return nil
+	}},
+	{"AUTH_KEYS_FILE", "auth-keys-file", "path of the API keys file, requests aren't authenticated without one", func(c *Config, v string) error {
+		c.Auth.KeysFile = v
+		return nil
+	}},
+}
+
+// Load func loads the Config from the config file, the environment and args (the command line flags
+// without the program name, ie. os.Args[1:]) and validates it
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("messari-server", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file (env CONFIG_FILE)")
+	flagged := map[string]string{}
+	for _, s := range settings {
+		name := s.flag
+		fs.Func(name, fmt.Sprintf("%s (env %s)", s.usage, s.env), func(v string) error {
+			flagged[name] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	if *path != "" {
+		if err := c.loadFile(*path); err != nil {
+			return nil, err
+		}
+		c.path = *path
+	}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok && v != "" {
+			if err := s.set(c, v); err != nil {
+				return nil, fmt.Errorf("invalid %s in env: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flagged[s.flag]; ok {
+			if err := s.set(c, v); err != nil {
+				return nil, fmt.Errorf("invalid -%s flag: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Path func returns the config file the Config was loaded from, or "" if there's none
+func (c *Config) Path() string {
+	return c.path
+}
+
+// ValidationError is returned by Validate with every problem found in a Config
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Validate func returns a ValidationError listing every invalid value in the Config
+func (c *Config) Validate() error {
+	var errs ValidationError
+	if c.Listen == "" {
+		errs = append(errs, "listen address is empty")
+	}
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Sprintf("shutdown timeout %s can't be negative", c.ShutdownTimeout))
+	}
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Sprintf("log level %q is not one of panic, fatal, error, warn, info, debug or trace", c.Log.Level))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Sprintf("log format %q is not text or json", c.Log.Format))
+	}
+	if len(c.Messari.Keys()) == 0 {
+		errs = append(errs, "messari api key is missing, set it in MESSARI_API_KEY in env")
+	}
+	if u, err := url.Parse(c.Messari.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Sprintf("messari base url %q is not an absolute http(s) URL", c.Messari.BaseURL))
+	}
+	if c.Messari.Timeout <= 0 {
+		errs = append(errs, fmt.Sprintf("messari timeout %s must be positive", c.Messari.Timeout))
+	}
+	if c.Messari.RequestsPerMinute <= 0 {
+		errs = append(errs, fmt.Sprintf("messari requests per minute %g must be positive", c.Messari.RequestsPerMinute))
+	}
+	if c.Messari.Burst < 1 {
+		errs = append(errs, fmt.Sprintf("messari burst %d must be at least 1", c.Messari.Burst))
+	}
+	if c.Messari.Concurrency < 1 {
+		errs = append(errs, fmt.Sprintf("messari concurrency %d must be at least 1", c.Messari.Concurrency))
+	}
+	if c.Messari.Passthrough.RequestsPerMinute <= 0 {
+		errs = append(errs, fmt.Sprintf("messari passthrough requests per minute %g must be positive", c.Messari.Passthrough.RequestsPerMinute))
+	}
+	if c.Messari.Passthrough.Burst < 1 {
+		errs = append(errs, fmt.Sprintf("messari passthrough burst %d must be at least 1", c.Messari.Passthrough.Burst))
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, "tracing endpoint is missing, the otlp exporter needs the collector's host:port")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("tracing exporter %q is not none, otlp or stdout", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Sprintf("tracing sample ratio %g must be between 0 and 1", c.Tracing.SampleRatio))
+	}
+	if c.RateLimit.RequestsPerMinute < 0 {
+		errs = append(errs, fmt.Sprintf("rate limit requests per minute %g can't be negative", c.RateLimit.RequestsPerMinute))
+	}
+	if c.RateLimit.RequestsPerMinute > 0 && c.RateLimit.Burst < 1 {
+		errs = append(errs, fmt.Sprintf("rate limit burst %d must be at least 1", c.RateLimit.Burst))
+	}
+	if c.RateLimit.MaxExpensive < 0 {
+		errs = append(errs, fmt.Sprintf("rate limit max expensive %d can't be negative", c.RateLimit.MaxExpensive))
+	}
+	routes := make([]string, 0, len(c.RateLimit.Costs))
+	for route := range c.RateLimit.Costs {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		switch cost := c.RateLimit.Costs[route]; {
+		case cost < 1:
+			errs = append(errs, fmt.Sprintf("rate limit cost %d of %s must be at least 1", cost, route))
+		case c.RateLimit.RequestsPerMinute > 0 && cost > c.RateLimit.Burst:
+			errs = append(errs, fmt.Sprintf("rate limit cost %d of %s is more than the burst %d, so it could never be requested", cost, route, c.RateLimit.Burst))
+		}
+	}
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Sprintf("rate limit trusted proxy %q is not an IP or CIDR", proxy))
+		}
+	}
+	if c.Cache.SnapshotTTL < time.Minute {
+		errs = append(errs, fmt.Sprintf("snapshot ttl %s must be at least 1m", c.Cache.SnapshotTTL))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// BaseURL func returns the parsed Messari.BaseURL, it's only valid to call on a validated Config
+func (c *Config) BaseURL() *url.URL {
+	u, _ := url.Parse(c.Messari.BaseURL)
+	return u
+}
+
+func parseDuration(v string, d *time.Duration) error {
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration (ie. 5m)", v)
+	}
+	*d = parsed
+	return nil
+}
+
+func parseFloat(v string, f *float64) error {
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", v)
+	}
+	*f = parsed
+	return nil
+}
+
+func parseBool(v string, b *bool) error {
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", v)
+	}
+	*b = parsed
+	return nil
+}
+
+func parseInt(v string, i *int) error {
+	parsed, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a whole number", v)
+	}
+	*i = parsed
+	return nil
+}
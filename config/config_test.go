@@ -0,0 +1,274 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setenv func sets the environment variable key to v for the rest of the test
+func setenv(t *testing.T, key, v string) {
+	t.Helper()
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, v)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+			return
+		}
+		os.Unsetenv(key)
+	})
+}
+
+// clearEnv func empties every environment variable Load reads for the rest of the test, so the
+// environment the tests run in doesn't change their Config
+func clearEnv(t *testing.T) {
+	t.Helper()
+	setenv(t, "CONFIG_FILE", "")
+	for _, s := range settings {
+		setenv(t, s.env, "")
+	}
+}
+
+// writeFile func writes content to a config file in a temporary directory and returns its path
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `
+listen: ":1"
+log:
+  level: warn
+messari:
+  api_key: file-api-key
+  requests_per_minute: 10
+`)
+	setenv(t, "LISTEN_ADDR", ":2")
+	setenv(t, "LOG_LEVEL", "error")
+
+	c, err := Load([]string{"-config", path, "-listen", ":3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Listen != ":3" {
+		t.Errorf("got listen %q, want the flag's", c.Listen)
+	}
+	if c.Log.Level != "error" {
+		t.Errorf("got log level %q, want the environment's", c.Log.Level)
+	}
+	if c.Messari.RequestsPerMinute != 10 || c.Messari.APIKey != "file-api-key" {
+		t.Errorf("got messari requests per minute %g and api key %q, want the file's", c.Messari.RequestsPerMinute, c.Messari.APIKey)
+	}
+	if c.Messari.Burst != Default().Messari.Burst {
+		t.Errorf("got messari burst %d, want the default", c.Messari.Burst)
+	}
+	if c.Path() != path {
+		t.Errorf("got path %q, want %q", c.Path(), path)
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	clearEnv(t)
+	setenv(t, "CONFIG_FILE", writeFile(t, "messari:\n  api_key: file-api-key\n"))
+	c, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Messari.APIKey != "file-api-key" {
+		t.Errorf("got api key %q, want the file's", c.Messari.APIKey)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "unknown key", file: "messari:\n  api_key: x\n  unknown: 1\n", want: "field unknown not found"},
+		{name: "missing file", args: []string{"-config", "does-not-exist.yaml"}, want: "could not read config file"},
+		{name: "invalid env", env: map[string]string{"MESSARI_API_KEY": "x", "MESSARI_BURST": "many"}, want: "invalid MESSARI_BURST in env"},
+		{name: "invalid flag", env: map[string]string{"MESSARI_API_KEY": "x"}, args: []string{"-messari-timeout", "soon"}, want: "invalid -messari-timeout flag"},
+		{name: "invalid value", env: map[string]string{"MESSARI_API_KEY": "x", "LOG_FORMAT": "xml"}, want: `log format "xml" is not text or json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				setenv(t, k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+			_, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("got %v for a valid config", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   []string
+	}{
+		{name: "api key", change: func(c *Config) { c.Messari.APIKey = "" }, want: []string{"messari api key is missing"}},
+		{name: "base url", change: func(c *Config) { c.Messari.BaseURL = "data.messari.io" }, want: []string{`messari base url "data.messari.io" is not an absolute http(s) URL`}},
+		{name: "cost over burst", change: func(c *Config) { c.RateLimit.Costs["/api/aggregate"] = 50 }, want: []string{"rate limit cost 50 of /api/aggregate is more than the burst 20"}},
+		{name: "trusted proxy", change: func(c *Config) { c.RateLimit.TrustedProxies = []string{"10.0.0.0/8", "proxy"} }, want: []string{`rate limit trusted proxy "proxy" is not an IP or CIDR`}},
+		{name: "ttl", change: func(c *Config) { c.Cache.SnapshotTTL = time.Second }, want: []string{"snapshot ttl 1s must be at least 1m"}},
+		{name: "several", change: func(c *Config) {
+			c.Listen = ""
+			c.Log.Level = "loud"
+			c.Tracing.SampleRatio = 2
+		}, want: []string{
+			"listen address is empty",
+			`log level "loud" is not one of`,
+			"tracing sample ratio 2 must be between 0 and 1",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.change(c)
+			var errs ValidationError
+			if err := c.Validate(); !errors.As(err, &errs) {
+				t.Fatalf("got %v, want a ValidationError", err)
+			}
+			if len(errs) != len(tt.want) {
+				t.Fatalf("got %q, want %d errors", errs, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(errs[i], want) {
+					t.Errorf("got %q, want %q", errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+// valid func returns the default Config with an API key, which makes it valid
+func valid() *Config {
+	c := Default()
+	c.Messari.APIKey = "test-api-key"
+	return c
+}
+
+func TestKeepOnReload(t *testing.T) {
+	old := valid()
+	c := valid()
+	c.Listen = ":9000"
+	c.Messari.Timeout = time.Minute
+	c.Tracing.Exporter = "stdout"
+	c.Auth.KeysFile = "keys.yaml"
+	c.Messari.RequestsPerMinute = 60
+
+	changed := c.keepOnReload(old)
+	want := []string{"listen", "auth.keys_file", "messari.timeout", "tracing"}
+	if strings.Join(changed, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", changed, want)
+	}
+	if c.Listen != old.Listen || c.Messari.Timeout != old.Messari.Timeout || c.Tracing != old.Tracing || c.Auth.KeysFile != old.Auth.KeysFile {
+		t.Error("values only applied on start weren't reset")
+	}
+	if c.Messari.RequestsPerMinute != 60 {
+		t.Error("value which can change while running was reset")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := valid()
+	c := valid()
+	if changes := Diff(old, c); len(changes) != 0 {
+		t.Fatalf("got %v for equal configs, want none", changes)
+	}
+
+	c.Messari.RequestsPerMinute = 60
+	c.Messari.APIKeys = []string{"other-api-key"}
+	c.Cache.SnapshotTTL = 10 * time.Minute
+	old.RateLimit.Costs = map[string]int{"/api/aggregate": 10}
+	c.RateLimit.Costs = map[string]int{"/api/aggregate": 5}
+	want := []string{
+		"messari.api_keys: \"\" -> [redacted]",
+		"messari.requests_per_minute: 30 -> 60",
+		"cache.snapshot_ttl: 5m0s -> 10m0s",
+		"rate_limit.costs: map[/api/aggregate:10] -> map[/api/aggregate:5]",
+	}
+	changes := Diff(old, c)
+	if len(changes) != len(want) {
+		t.Fatalf("got %v, want %d changes", changes, len(want))
+	}
+	for i := range want {
+		if changes[i].String() != want[i] {
+			t.Errorf("got %q, want %q", changes[i], want[i])
+		}
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "listen: \":1\"\nmessari:\n  api_key: file-api-key\n")
+	args := []string{"-config", path}
+	c, err := Load(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(c, args)
+	var reloads int
+	s.OnChange(func(old, new *Config) {
+		reloads++
+		if old != c || new != s.Config() {
+			t.Error("listener wasn't given the old and the new config")
+		}
+	})
+
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if reloads != 0 || s.Config() != c {
+		t.Fatal("reload without changes replaced the config")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("listen: \":2\"\nmessari:\n  api_key: file-api-key\n  burst: 10\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if reloads != 1 {
+		t.Fatalf("got %d calls of the listener, want 1", reloads)
+	}
+	if got := s.Config(); got.Messari.Burst != 10 || got.Listen != ":1" {
+		t.Errorf("got burst %d and listen %q, want the new burst and the old listen address", got.Messari.Burst, got.Listen)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("messari:\n  burst: 0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err == nil {
+		t.Error("got no error reloading an invalid config")
+	}
+	if s.Config().Messari.Burst != 10 {
+		t.Error("invalid config replaced the current one")
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change struct is a single value which differs between two Configs
+type Change struct {
+	// Path is the value's path in the config file (ie. "messari.requests_per_minute")
+	Path string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff func returns every value which differs between old and new. Values tagged with redact
+// (ie. API keys) are never included as is.
+func Diff(old, new *Config) []Change {
+	return diff("", reflect.ValueOf(*old), reflect.ValueOf(*new), false)
+}
+
+func diff(prefix string, old, new reflect.Value, redact bool) []Change {
+	if old.Kind() == reflect.Struct {
+		var changes []Change
+		for i := 0; i < old.NumField(); i++ {
+			sf := old.Type().Field(i)
+			name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+			if sf.PkgPath != "" || name == "-" {
+				continue
+			}
+			changes = append(changes, diff(prefix+name+".", old.Field(i), new.Field(i), sf.Tag.Get("redact") == "true")...)
+		}
+		return changes
+	}
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return nil
+	}
+	change := Change{Path: strings.TrimSuffix(prefix, "."), Old: fmt.Sprint(old.Interface()), New: fmt.Sprint(new.Interface())}
+	if redact {
+		change.Old, change.New = redacted(change.Old), redacted(change.New)
+	}
+	return []Change{change}
+}
+
+func redacted(v string) string {
+	if v == "" || v == "[]" {
+		return `""`
+	}
+	return "[redacted]"
+}
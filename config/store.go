@@ -0,0 +1,162 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// reloadDelay is how long WatchFile waits for writes to a file to settle before reporting it changed,
+// editors often write a file in several steps
+const reloadDelay = 250 * time.Millisecond
+
+// Store struct holds the current Config and replaces it as a whole when the config file changes, so
+// anything holding a *Config from Config sees a consistent set of values
+type Store struct {
+	current atomic.Value
+	args    []string
+
+	mu        sync.Mutex
+	listeners []func(old, new *Config)
+}
+
+// NewStore func returns a Store holding c, which was loaded by Load from args
+func NewStore(c *Config, args []string) *Store {
+	s := &Store{args: args}
+	s.current.Store(c)
+	return s
+}
+
+// Config func returns the current Config. It must not be modified.
+func (s *Store) Config() *Config {
+	return s.current.Load().(*Config)
+}
+
+// OnChange func registers fn to be called with the old and the new Config after each reload
+// which changed something
+func (s *Store) OnChange(fn func(old, new *Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Reload func loads the Config again from the config file, the environment and the flags. Values
+// which can only be applied on start (ie. the listen address) keep their current value.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.Config()
+	c, err := Load(s.args)
+	if err != nil {
+		return err
+	}
+	for _, restart := range c.keepOnReload(old) {
+		logrus.Warnf("config %s changed but only applies after a restart", restart)
+	}
+
+	changes := Diff(old, c)
+	if len(changes) == 0 {
+		return nil
+	}
+	s.current.Store(c)
+	for _, change := range changes {
+		logrus.Infof("config changed %s", change)
+	}
+	for _, fn := range s.listeners {
+		fn(old, c)
+	}
+	return nil
+}
+
+// keepOnReload func resets the values which can't be changed while running to old's,
+// returning the paths of those which were changed
+func (c *Config) keepOnReload(old *Config) []string {
+	var changed []string
+	keep := func(path string, v *string, oldV string) {
+		if *v != oldV {
+			changed = append(changed, path)
+			*v = oldV
+		}
+	}
+	keep("listen", &c.Listen, old.Listen)
+	keep("messari.base_url", &c.Messari.BaseURL, old.Messari.BaseURL)
+	keep("rates_file", &c.RatesFile, old.RatesFile)
+	keep("auth.keys_file", &c.Auth.KeysFile, old.Auth.KeysFile)
+	if c.Messari.Timeout != old.Messari.Timeout {
+		changed = append(changed, "messari.timeout")
+		c.Messari.Timeout = old.Messari.Timeout
+	}
+	if c.Tracing != old.Tracing {
+		changed = append(changed, "tracing")
+		c.Tracing = old.Tracing
+	}
+	if c.Messari.Concurrency != old.Messari.Concurrency {
+		changed = append(changed, "messari.concurrency")
+		c.Messari.Concurrency = old.Messari.Concurrency
+	}
+	return changed
+}
+
+// Watch func reloads the Config whenever its config file changes until ctx is done. It returns
+// right away if the Config wasn't loaded from a file.
+func (s *Store) Watch(ctx context.Context) error {
+	path := s.Config().Path()
+	if path == "" {
+		return nil
+	}
+	return WatchFile(ctx, path, func() {
+		if err := s.Reload(); err != nil {
+			logrus.Errorf("could not reload config, keeping the current one: %v", err)
+		}
+	})
+}
+
+// WatchFile func calls changed whenever the file at path changes until ctx is done
+func WatchFile(ctx context.Context, path string, changed func()) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("could not resolve path of %s: %w", path, err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create watcher of %s: %w", path, err)
+	}
+	defer watcher.Close()
+	// the directory is watched rather than the file since editors and Kubernetes' config maps
+	// replace the file instead of writing to it
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("could not watch %s: %w", path, err)
+	}
+
+	reload := time.NewTimer(reloadDelay)
+	reload.Stop()
+	defer reload.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == path || filepath.Base(event.Name) == "..data" {
+				reload.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logrus.Errorf("watcher of %s failed: %v", path, err)
+		case <-reload.C:
+			changed()
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/taxonomy"
+)
+
+// aliases are short names for commonly filtered fields
+var aliases = map[string]string{
+	"marketcap":        "metrics.marketcap.current_marketcap_usd",
+	"liquid_marketcap": "metrics.marketcap.liquid_marketcap_usd",
+	"volume":           "metrics.market_data.volume_last_24_hours",
+	"real_volume":      "metrics.market_data.real_volume_last_24_hours",
+	"price":            "metrics.market_data.price_usd",
+	"change_1h":        "metrics.market_data.percent_change_usd_last_1_hour",
+	"change_24h":       "metrics.market_data.percent_change_usd_last_24_hours",
+	"sector":           "profile.general.overview.sector",
+	"category":         "profile.general.overview.category",
+	"tagline":          "profile.general.overview.tagline",
+}
+
+// computed are fields which aren't in messari.Asset as is, but derived from several of its fields
+var computed = map[string]func(messari.Asset) []string{
+	"sectors": func(asset messari.Asset) []string {
+		return taxonomy.Of(asset).Sectors
+	},
+	"categories": func(asset messari.Asset) []string {
+		return taxonomy.Of(asset).Categories
+	},
+	"tags": func(asset messari.Asset) []string {
+		return taxonomy.Of(asset).Tags
+	},
+}
+
+var assetType = reflect.TypeOf(messari.Asset{})
+
+// step is a single level of a Field's path, either a struct field index or a map key
+type step struct {
+	index []int
+	key   string
+}
+
+// Field struct is a path into a messari.Asset resolved ahead of time
+type Field struct {
+	// Path is the full path of the field from the root of the Asset (ie. "metrics.marketcap.current_marketcap_usd")
+	Path    string
+	steps   []step
+	kind    reflect.Kind
+	compute func(messari.Asset) []string
+}
+
+// ResolveField func resolves name to a Field of messari.Asset. Besides the aliases (ie. "marketcap") and
+// the normalized "sectors", "categories" and "tags" of the taxonomy package, name
+// is a path of json field names seperated by "." or "/" which is looked up from the root of the Asset, then
+// in its Metrics and then in its Profile, so "risk_metrics.sharpe_ratios.last_30_days" resolves to
+// "metrics.risk_metrics.sharpe_ratios.last_30_days".
+func ResolveField(name string) (*Field, error) {
+	path := strings.ToLower(strings.Trim(strings.ReplaceAll(name, "/", "."), "."))
+	if compute, ok := computed[path]; ok {
+		return &Field{Path: path, kind: reflect.Slice, compute: compute}, nil
+	}
+	if alias, ok := aliases[path]; ok {
+		path = alias
+	}
+	if path == "" {
+		return nil, fmt.Errorf("field name is empty")
+	}
+	for _, prefix := range []string{"", "metrics.", "profile."} {
+		if f, ok := resolvePath(prefix + path); ok {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown field %q", name)
+}
+
+func resolvePath(path string) (*Field, bool) {
+	f := &Field{Path: path}
+	t := assetType
+	for _, name := range strings.Split(path, ".") {
+		t = indirect(t)
+		switch t.Kind() {
+		case reflect.Struct:
+			sf, ok := fieldByJSONName(t, name)
+			if !ok {
+				return nil, false
+			}
+			f.steps = append(f.steps, step{index: sf.Index})
+			t = sf.Type
+		case reflect.Map:
+			f.steps = append(f.steps, step{key: name})
+			t = t.Elem()
+		default:
+			return nil, false
+		}
+	}
+	t = indirect(t)
+	f.kind = t.Kind()
+	if f.kind == reflect.Struct || f.kind == reflect.Map {
+		// only leaves can be compared
+		return nil, false
+	}
+	return f, true
+}
+
+// Value func returns the value of the Field in asset as a float64, string, bool or []string, and false
+// if the asset doesn't have a value for it
+func (f *Field) Value(asset messari.Asset) (interface{}, bool) {
+	if f.compute != nil {
+		values := f.compute(asset)
+		return values, len(values) > 0
+	}
+	v := reflect.ValueOf(asset)
+	for _, s := range f.steps {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+		if s.key != "" {
+			v = v.MapIndex(reflect.ValueOf(s.key))
+			if !v.IsValid() {
+				return nil, false
+			}
+			continue
+		}
+		v = v.FieldByIndex(s.index)
+	}
+	return plain(v)
+}
+
+// Number func returns the value of the Field in asset if it's a number
+func (f *Field) Number(asset messari.Asset) (float64, bool) {
+	v, ok := f.Value(asset)
+	if !ok {
+		return 0, false
+	}
+	n, ok := v.(float64)
+	return n, ok
+}
+
+// Numeric func returns whether the Field holds a number
+func (f *Field) Numeric() bool {
+	switch f.kind {
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func plain(v reflect.Value) (interface{}, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Slice:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if s, ok := plain(v.Index(i)); ok {
+				values = append(values, fmt.Sprint(s))
+			}
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous {
+			if inner, ok := fieldByJSONName(indirect(sf.Type), name); ok {
+				inner.Index = append([]int{i}, inner.Index...)
+				return inner, true
+			}
+			continue
+		}
+		if strings.Split(sf.Tag.Get("json"), ",")[0] == name {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,214 @@
+// Package filter implements a small expression language for filtering assets on any field of their
+// metrics and profile, for example:
+//
+//	sector in ("DeFi", "Exchange") and marketcap > 1e8 and tags contains "Lending"
+//
+// Comparisons (=, !=, >, >=, <, <=, in, contains) can be combined with and, or, not and parentheses.
+// String comparisons ignore case, and "field = null" matches assets without a value for the field.
+// Comparing a list (ie. tags) with = or contains matches when any of its values is equal.
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ultd/messari-server/messari"
+)
+
+// Filter struct is a parsed filter expression
+type Filter struct {
+	src  string
+	root node
+}
+
+// Parse func parses the filter expression src, returning an error describing where it's invalid
+func Parse(src string) (*Filter, error) {
+	p := &parser{lexer: newLexer(src)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &Filter{src: src, root: root}, nil
+}
+
+// And func returns a Filter which only matches assets matching all of filters. Nil filters are ignored.
+func And(filters ...*Filter) *Filter {
+	var combined *Filter
+	for _, f := range filters {
+		if f == nil {
+			continue
+		}
+		if combined == nil {
+			combined = f
+			continue
+		}
+		combined = &Filter{
+			src:  fmt.Sprintf("(%s) and (%s)", combined.src, f.src),
+			root: &logical{and: true, left: combined.root, right: f.root},
+		}
+	}
+	return combined
+}
+
+// Match func returns whether asset matches the Filter. A nil Filter matches every asset.
+func (f *Filter) Match(asset messari.Asset) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.eval(asset)
+}
+
+// String func returns the source of the Filter
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.src
+}
+
+// Equals func returns a Filter matching assets where field is equal to value, ignoring case
+func Equals(field string, value string) (*Filter, error) {
+	return Parse(fmt.Sprintf("%s = %s", field, quote(value)))
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+type node interface {
+	eval(asset messari.Asset) bool
+}
+
+type logical struct {
+	and         bool
+	left, right node
+}
+
+func (n *logical) eval(asset messari.Asset) bool {
+	if n.and {
+		return n.left.eval(asset) && n.right.eval(asset)
+	}
+	return n.left.eval(asset) || n.right.eval(asset)
+}
+
+type not struct {
+	operand node
+}
+
+func (n *not) eval(asset messari.Asset) bool {
+	return !n.operand.eval(asset)
+}
+
+type comparison struct {
+	field  *Field
+	op     string
+	values []literal
+}
+
+func (n *comparison) eval(asset messari.Asset) bool {
+	v, ok := n.field.Value(asset)
+	if n.values[0].null {
+		if n.op == "=" {
+			return !ok
+		}
+		return ok
+	}
+	if !ok {
+		return false
+	}
+
+	switch n.op {
+	case "in":
+		for _, l := range n.values {
+			if equal(v, l) {
+				return true
+			}
+		}
+		return false
+	case "contains":
+		return contains(v, n.values[0])
+	case "=":
+		return equal(v, n.values[0])
+	case "!=":
+		return !equal(v, n.values[0])
+	}
+	c, ok := compare(v, n.values[0])
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// literal is a value in a filter expression
+type literal struct {
+	null    bool
+	number  *float64
+	str     *string
+	boolean *bool
+}
+
+func equal(v interface{}, l literal) bool {
+	switch v := v.(type) {
+	case float64:
+		return l.number != nil && v == *l.number
+	case string:
+		return l.str != nil && strings.EqualFold(strings.TrimSpace(v), *l.str)
+	case bool:
+		return l.boolean != nil && v == *l.boolean
+	case []string:
+		for _, s := range v {
+			if equal(s, l) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(v interface{}, l literal) bool {
+	switch v := v.(type) {
+	case string:
+		return l.str != nil && strings.Contains(strings.ToLower(v), strings.ToLower(*l.str))
+	case []string:
+		return equal(v, l)
+	}
+	return false
+}
+
+func compare(v interface{}, l literal) (int, bool) {
+	switch v := v.(type) {
+	case float64:
+		if l.number == nil {
+			return 0, false
+		}
+		switch {
+		case v < *l.number:
+			return -1, true
+		case v > *l.number:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if l.str == nil {
+			return 0, false
+		}
+		return strings.Compare(strings.ToLower(v), strings.ToLower(*l.str)), true
+	}
+	return 0, false
+}
@@ -0,0 +1,136 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ultd/messari-server/messari"
+)
+
+// testAsset is the asset TestMatch evaluates filters against
+const testAsset = `{
+	"symbol": "AAVE",
+	"metrics": {
+		"market_data": {"price_usd": 250, "percent_change_usd_last_24_hours": -2.5},
+		"marketcap": {"current_marketcap_usd": 3000000000},
+		"misc_data": {"tags": ["Governance"]}
+	},
+	"profile": {"general": {"overview": {
+		"is_verified": true,
+		"sector": "Lending",
+		"tags": "DeFi, Lending Protocols"
+	}}}
+}`
+
+func TestMatch(t *testing.T) {
+	var asset messari.Asset
+	if err := json.Unmarshal([]byte(testAsset), &asset); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		// comparisons of numbers
+		{src: "marketcap > 1e9", want: true},
+		{src: "marketcap >= 3_000_000_000", want: true},
+		{src: "marketcap < 3e9", want: false},
+		{src: "marketcap <= 3e9", want: true},
+		{src: "change_24h < 0", want: true},
+		{src: "price = 250", want: true},
+		{src: "price != 250", want: false},
+		// comparisons of text ignore case and surrounding spaces
+		{src: `sector = "lending"`, want: true},
+		{src: `sector != "Lending"`, want: false},
+		{src: `sector > "Exchange"`, want: true},
+		{src: `sector < "exchange"`, want: false},
+		{src: `symbol >= "aave"`, want: true},
+		// booleans
+		{src: "general.overview.is_verified = true", want: true},
+		{src: "general.overview.is_verified != true", want: false},
+		// in
+		{src: `sector in ("DeFi", "lending")`, want: true},
+		{src: `sector in ("DeFi", "Exchange")`, want: false},
+		{src: "price in (1, 250)", want: true},
+		{src: `tags in ("Payments", "governance")`, want: true},
+		// contains
+		{src: `tagline contains "x"`, want: false},
+		{src: `sector contains "END"`, want: true},
+		{src: `tags contains "Lending Protocols"`, want: true},
+		{src: `tags contains "Lending"`, want: false},
+		{src: `tags = "defi"`, want: true},
+		// null
+		{src: "tagline = null", want: true},
+		{src: "tagline != null", want: false},
+		{src: "sector = null", want: false},
+		{src: "sector != null", want: true},
+		{src: "categories = null", want: true},
+		// a missing value only matches null
+		{src: `tagline != "x"`, want: false},
+		{src: "liquid_marketcap < 1", want: false},
+		// not, and, or
+		{src: "not marketcap > 1e9", want: false},
+		{src: "not not marketcap > 1e9", want: true},
+		{src: `not (sector = "Exchange" or price > 1000)`, want: true},
+		{src: `sector = "Lending" and price > 1000`, want: false},
+		{src: `sector = "Exchange" or price < 1000`, want: true},
+		{src: `sector = "Exchange" and price > 1 or marketcap > 1`, want: true},
+		{src: `sector = "Exchange" and (price > 1 or marketcap > 1)`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			f, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got := f.Match(asset); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMismatchedTypes(t *testing.T) {
+	for _, src := range []string{
+		`marketcap > "1e9"`,
+		"marketcap = true",
+		`marketcap contains 1`,
+		"sector = 1",
+		`sector in ("DeFi", 1)`,
+		`general.overview.is_verified = "true"`,
+		"general.overview.is_verified > false",
+		"tags = true",
+		"marketcap > null",
+		`sector contains null`,
+	} {
+		t.Run(src, func(t *testing.T) {
+			if _, err := Parse(src); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestAnd(t *testing.T) {
+	var asset messari.Asset
+	if err := json.Unmarshal([]byte(testAsset), &asset); err != nil {
+		t.Fatal(err)
+	}
+	matching, _ := Parse("marketcap > 1")
+	failing, _ := Parse("price > 1000")
+
+	if f := And(nil, nil); f != nil || !f.Match(asset) {
+		t.Errorf("got %v, want a nil Filter matching every asset", f)
+	}
+	if f := And(nil, matching); f != matching {
+		t.Errorf("got %v, want the only filter", f)
+	}
+	f := And(matching, nil, failing)
+	if f.Match(asset) {
+		t.Error("got a match, want none since one of the filters fails")
+	}
+	if want := "(marketcap > 1) and (price > 1000)"; f.String() != want {
+		t.Errorf("got %q, want %q", f.String(), want)
+	}
+}
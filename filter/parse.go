@@ -0,0 +1,367 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError is returned by Parse when a filter expression is invalid
+type SyntaxError struct {
+	// Pos is the byte offset in the expression where the error was found
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// keyword func returns whether the token is the (case insensitive) keyword kw
+func (t token) keyword(kw string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, kw)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokenLParen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokenRParen, text: ")", pos: start}, nil
+	case c == ',':
+		l.pos++
+		return token{kind: tokenComma, text: ",", pos: start}, nil
+	case c == '"' || c == '\'':
+		return l.string(c)
+	case strings.IndexByte("=!<>", c) >= 0:
+		l.pos++
+		if l.pos < len(l.src) && l.src[l.pos] == '=' {
+			l.pos++
+		}
+		op := l.src[start:l.pos]
+		switch op {
+		case "==":
+			op = "="
+		case "!":
+			return token{}, &SyntaxError{Pos: start, Msg: `expected "!="`}
+		}
+		return token{kind: tokenOperator, text: op, pos: start}, nil
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return l.number()
+	case isIdentChar(c):
+		for l.pos < len(l.src) && (isIdentChar(l.src[l.pos]) || l.src[l.pos] == '.' || l.src[l.pos] == '/') {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], pos: start}, nil
+	}
+	return token{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+}
+
+func (l *lexer) string(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\' && l.pos+1 < len(l.src):
+			b.WriteByte(l.src[l.pos+1])
+			l.pos += 2
+		case c == quote:
+			l.pos++
+			return token{kind: tokenString, text: b.String(), pos: start}, nil
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, &SyntaxError{Pos: start, Msg: "string is missing its closing quote"}
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		// a sign is only part of the number at its start or in its exponent
+		sign := (c == '-' || c == '+') && (l.pos == start || l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E')
+		if (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '_' || sign {
+			l.pos++
+			continue
+		}
+		break
+	}
+	text := l.src[start:l.pos]
+	if !validSeparators(text) {
+		return token{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("invalid number %q, _ must be between digits", text)}
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64); err != nil {
+		return token{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("invalid number %q", text)}
+	}
+	return token{kind: tokenNumber, text: text, pos: start}, nil
+}
+
+// validSeparators func returns whether every _ in the number text is between two digits, as in Go
+func validSeparators(text string) bool {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	for i := 0; i < len(text); i++ {
+		if text[i] == '_' && (i == 0 || i == len(text)-1 || !isDigit(text[i-1]) || !isDigit(text[i+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+func (p *parser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses: and ("or" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.keyword("or") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: not ("and" not)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.keyword("and") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseNot parses: "not" not | "(" or ")" | comparison
+func (p *parser) parseNot() (node, error) {
+	if p.tok.keyword("not") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{operand: operand}, nil
+	}
+	if p.tok.kind == tokenLParen {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRParen {
+			return nil, p.errorf("expected \")\" but found %s", p.tok)
+		}
+		return n, p.next()
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses: field operator value | field "in" "(" value ("," value)* ")" | field "contains" value
+func (p *parser) parseComparison() (node, error) {
+	if p.tok.kind != tokenIdent {
+		return nil, p.errorf("expected a field name but found %s", p.tok)
+	}
+	field, err := ResolveField(p.tok.text)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	n := &comparison{field: field}
+	opPos := p.tok.pos
+	switch {
+	case p.tok.kind == tokenOperator:
+		n.op = p.tok.text
+	case p.tok.keyword("in"):
+		n.op = "in"
+	case p.tok.keyword("contains"):
+		n.op = "contains"
+	default:
+		return nil, p.errorf("expected an operator after %s but found %s", field.Path, p.tok)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if n.op == "in" {
+		if p.tok.kind != tokenLParen {
+			return nil, p.errorf("expected \"(\" after in but found %s", p.tok)
+		}
+		for {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			l, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, l)
+			if p.tok.kind == tokenRParen {
+				break
+			}
+			if p.tok.kind != tokenComma {
+				return nil, p.errorf("expected \",\" or \")\" but found %s", p.tok)
+			}
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	} else {
+		l, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		n.values = []literal{l}
+	}
+
+	if err := checkTypes(n); err != nil {
+		return nil, &SyntaxError{Pos: opPos, Msg: err.Error()}
+	}
+	return n, nil
+}
+
+func (p *parser) parseLiteral() (literal, error) {
+	var l literal
+	switch {
+	case p.tok.kind == tokenString:
+		s := p.tok.text
+		l.str = &s
+	case p.tok.kind == tokenNumber:
+		v, _ := strconv.ParseFloat(strings.ReplaceAll(p.tok.text, "_", ""), 64)
+		l.number = &v
+	case p.tok.keyword("true"), p.tok.keyword("false"):
+		v := p.tok.keyword("true")
+		l.boolean = &v
+	case p.tok.keyword("null"):
+		l.null = true
+	default:
+		return l, p.errorf("expected a value but found %s", p.tok)
+	}
+	return l, p.next()
+}
+
+// checkTypes func makes sure the values of a comparison can be compared with its field
+func checkTypes(n *comparison) error {
+	for _, l := range n.values {
+		if l.null {
+			if n.op != "=" && n.op != "!=" {
+				return fmt.Errorf("null can only be compared using = or !=")
+			}
+			continue
+		}
+		switch {
+		case n.field.Numeric():
+			if l.number == nil {
+				return fmt.Errorf("%s is a number", n.field.Path)
+			}
+			if n.op == "contains" {
+				return fmt.Errorf("contains can't be used on %s which is a number", n.field.Path)
+			}
+		case n.field.kind == reflect.Bool:
+			if l.boolean == nil || (n.op != "=" && n.op != "!=") {
+				return fmt.Errorf("%s is a boolean which can only be compared to true or false using = or !=", n.field.Path)
+			}
+		case n.field.kind == reflect.String || n.field.kind == reflect.Slice:
+			if l.str == nil {
+				return fmt.Errorf("%s is text, its value has to be quoted", n.field.Path)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLexerNumber(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+		// rest is what's left of src after the number
+		rest    string
+		invalid bool
+	}{
+		{src: "5", want: "5"},
+		{src: "5 > marketcap", want: "5", rest: " > marketcap"},
+		{src: "-1", want: "-1"},
+		{src: "+1.5", want: "+1.5"},
+		{src: ".5", want: ".5"},
+		{src: "1e9", want: "1e9"},
+		{src: "1.5E-3", want: "1.5E-3"},
+		{src: "2e+6)", want: "2e+6", rest: ")"},
+		{src: "1_000_000", want: "1_000_000"},
+		{src: "1-2", want: "1", rest: "-2"},
+		{src: ".", invalid: true},
+		{src: "-", invalid: true},
+		{src: "--1", invalid: true},
+		{src: "1.2.3", invalid: true},
+		{src: "1e", invalid: true},
+		{src: "1_", invalid: true},
+		{src: "1__0", invalid: true},
+		{src: "1_.5", invalid: true},
+		{src: "-_1", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			l := newLexer(tt.src)
+			tok, err := l.next()
+			if tt.invalid {
+				var syntaxErr *SyntaxError
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("got token %v and error %v, want a SyntaxError", tok, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if tok.kind != tokenNumber || tok.text != tt.want {
+				t.Errorf("got token %v, want number %q", tok, tt.want)
+			}
+			if rest := tt.src[l.pos:]; rest != tt.rest {
+				t.Errorf("got %q left, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src     string
+		invalid bool
+	}{
+		{src: "marketcap > 5"},
+		{src: "marketcap > -1"},
+		{src: "marketcap >= 1_000_000_000"},
+		{src: "marketcap < 1.5e12 and change_24h > -2.5"},
+		{src: "price in (1, .5, -1e-3)"},
+		{src: "5 > marketcap", invalid: true},
+		{src: "-1", invalid: true},
+		{src: ".", invalid: true},
+		{src: "+", invalid: true},
+		{src: "1e", invalid: true},
+		{src: "marketcap > 1e", invalid: true},
+		{src: "marketcap > 1__0", invalid: true},
+		{src: "marketcap > --1", invalid: true},
+		{src: "marketcap > 1.2.3", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			if !tt.invalid {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				return
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("got error %v, want a SyntaxError", err)
+			}
+		})
+	}
+}
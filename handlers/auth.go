@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/auth"
+	"github.com/ultd/messari-server/logging"
+)
+
+// apiKeyHeader is the header a request can carry its API key in, instead of Authorization
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyKey is the key of the request's *auth.Key in the gin.Context
+const apiKeyKey = "api_key"
+
+// AuthMiddleware func returns a middleware letting through only requests carrying one of keys'
+// API keys, either as "Authorization: Bearer <key>" or in the X-API-Key header, which may request the
+// route. The key's ID is added to the request's log lines. Quotas are charged by QuotaMiddleware.
+func AuthMiddleware(keys *auth.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		k, ok := keys.Authenticate(apiKeyOf(ctx.Request))
+		if !ok {
+			ctx.Header("WWW-Authenticate", `Bearer realm="messari-server"`)
+			respondError(ctx, http.StatusUnauthorized, apiError{
+				Code:    codeUnauthorized,
+				Message: "A valid API key is required, pass it as \"Authorization: Bearer <key>\" or in the X-API-Key header.",
+			})
+			return
+		}
+		ctx.Set(apiKeyKey, k)
+		entry := logging.From(ctx.Request.Context()).WithField("key_id", k.ID)
+		ctx.Request = ctx.Request.WithContext(logging.WithLogger(ctx.Request.Context(), entry))
+
+		route := ctx.FullPath()
+		if !k.Allows(route) {
+			respondError(ctx, http.StatusForbidden, apiError{
+				Code:    codeForbidden,
+				Message: fmt.Sprintf("API key %s may not request %s.", k.ID, route),
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// QuotaMiddleware func returns a middleware charging a request authenticated by AuthMiddleware to its
+// key's quota, refusing it once the quota is used up. It goes after the inbound rate limits so
+// requests they shed don't use up quota.
+func QuotaMiddleware(keys *auth.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		v, _ := ctx.Get(apiKeyKey)
+		k, ok := v.(*auth.Key)
+		if !ok {
+			ctx.Next()
+			return
+		}
+		if ok, retryAfter := keys.Use(k, ctx.FullPath(), time.Now()); !ok {
+			setRetryAfter(ctx, retryAfter)
+			respondError(ctx, http.StatusTooManyRequests, apiError{
+				Code:      codeQuotaExceeded,
+				Message:   fmt.Sprintf("API key %s has used up its quota of %d requests per %s.", k.ID, k.Quota.Requests, k.Quota.Period),
+				Retryable: true,
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// apiKeyOf func returns the API key r carries, "" if there's none
+func apiKeyOf(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if parts := strings.SplitN(h, " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+			return strings.TrimSpace(parts[1])
+		}
+		return ""
+	}
+	return r.Header.Get(apiKeyHeader)
+}
+
+// RequireAdmin func returns a middleware letting through only requests authenticated by
+// AuthMiddleware with an admin key
+func RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		v, _ := ctx.Get(apiKeyKey)
+		if k, ok := v.(*auth.Key); !ok || !k.Admin {
+			respondError(ctx, http.StatusForbidden, apiError{Code: codeForbidden, Message: "An admin API key is required."})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// UsageHandler func returns a handler listing how much each of keys' API keys has been used
+func UsageHandler(keys *auth.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"keys": keys.Usage(time.Now())})
+	}
+}
+
+// KeyUsageHandler func returns a handler reporting how much the API key with the :id param has been used
+func KeyUsageHandler(keys *auth.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		usage, ok := keys.UsageOf(id, time.Now())
+		if !ok {
+			respondError(ctx, http.StatusNotFound, apiError{Code: codeNotFound, Message: fmt.Sprintf("There's no API key %s.", id)})
+			return
+		}
+		ctx.JSON(http.StatusOK, usage)
+	}
+}
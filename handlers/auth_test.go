@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/auth"
+	"github.com/ultd/messari-server/config"
+	"github.com/ultd/messari-server/ratelimit"
+)
+
+func TestQuotaIsChargedAfterRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	keysFile := "keys:\n  - id: a\n    hash: " + auth.Hash("some-key") + "\n    quota: {requests: 5, period: 1h}"
+	if err := ioutil.WriteFile(path, []byte(keysFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	cfg := testConfig(nil)
+	server.Use(withConfig(func() *config.Config { return cfg }), AuthMiddleware(keys), RateLimitMiddleware(ratelimit.New(60, 1, 1)), QuotaMiddleware(keys))
+	server.GET("/api/search", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+		req.Header.Set(apiKeyHeader, "some-key")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("request #%d: got status %d, want %d", i+1, rec.Code, want)
+		}
+	}
+
+	u, _ := keys.UsageOf("a", time.Now())
+	if u.Requests != 1 || u.Rejected != 0 || u.Quota.Remaining != 4 {
+		t.Errorf("got %d requests, %d rejected and %d remaining, want only the request let through charged", u.Requests, u.Rejected, u.Quota.Remaining)
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/quote"
+	"github.com/ultd/messari-server/taxonomy"
+	"github.com/ultd/messari-server/universe"
+)
+
+const (
+	defaultTopConstituents = 5
+	maxTopConstituents     = 50
+)
+
+// breakdownGroupers maps the "by" query param of GetAssetMetricsBreakdownHandler to a func
+// returning the groups an asset belongs to
+var breakdownGroupers = map[string]func(messari.Asset) []string{
+	"sector": func(asset messari.Asset) []string {
+		return taxonomy.Of(asset).Sectors
+	},
+	"category": func(asset messari.Asset) []string {
+		return taxonomy.Of(asset).Categories
+	},
+	"tags": func(asset messari.Asset) []string {
+		return taxonomy.Of(asset).Tags
+	},
+}
+
+// assetBreakdown struct is the response json of GetAssetMetricsBreakdownHandler
+type assetBreakdown struct {
+	By         string              `json:"by"`
+	Weighting  string              `json:"weighting"`
+	Quote      string              `json:"quote"`
+	Groups     []assetBreakdownRow `json:"groups"`
+	SnapshotAt time.Time           `json:"snapshotAt"`
+}
+
+// assetBreakdownRow struct holds the aggregated metrics of a single group of assets
+type assetBreakdownRow struct {
+	Group                      string             `json:"group"`
+	AssetCount                 int                `json:"assetCount"`
+	MarketCap                  float64            `json:"marketcap"`
+	Volume                     float64            `json:"volume"`
+	MeanTwentyFourHourChange   float64            `json:"mean24HourChange"`
+	MedianTwentyFourHourChange float64            `json:"median24HourChange"`
+	TwentyFourHourChangeStats  changeStats        `json:"24HourChangeStats"`
+	TopConstituents            []assetConstituent `json:"topConstituents"`
+}
+
+// assetConstituent struct is one of the largest assets of a group in assetBreakdownRow
+type assetConstituent struct {
+	ID                   string  `json:"id"`
+	Symbol               string  `json:"symbol"`
+	Name                 string  `json:"name"`
+	MarketCap            float64 `json:"marketcap"`
+	TwentyFourHourChange float64 `json:"24HourChange"`
+}
+
+// GetAssetMetricsBreakdownHandler func returns a handler for getting the aggregated metrics of every asset
+// grouped by sector, category or tag, one row per group
+func GetAssetMetricsBreakdownHandler(snapshot *universe.Snapshot, fx *quote.Table) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		by := ctx.Query("by")
+		groupsOf, ok := breakdownGroupers[by]
+		if !ok {
+			badRequest(ctx, "Invalid by specified in query, expected one of sector, tags or category.")
+			return
+		}
+		top := defaultTopConstituents
+		if t := ctx.Query("top"); t != "" {
+			v, err := strconv.Atoi(t)
+			if err != nil || v < 0 || v > maxTopConstituents {
+				badRequest(ctx, "Invalid top specified in query.")
+				return
+			}
+			top = v
+		}
+		weighting, ok := queryWeighting(ctx)
+		if !ok {
+			return
+		}
+		currency, ok := queryQuote(ctx, fx)
+		if !ok {
+			return
+		}
+
+		assets, snapshotAt := snapshot.Assets()
+		if snapshotAt.IsZero() {
+			notReady(ctx, "Asset snapshot is still loading, try again shortly.")
+			return
+		}
+		assets, ok = quoteAssets(ctx, fx, currency, assets, assets)
+		if !ok {
+			return
+		}
+
+		// assets are sorted by market cap, so each group's assets are too
+		// groups are keyed by taxonomy.Key so their names' case doesn't split them
+		groups := map[string][]messari.Asset{}
+		names := map[string]string{}
+		for _, asset := range assets {
+			for _, group := range groupsOf(asset) {
+				key := taxonomy.Key(group)
+				if _, ok := names[key]; !ok {
+					names[key] = group
+				}
+				groups[key] = append(groups[key], asset)
+			}
+		}
+
+		rows := make([]assetBreakdownRow, 0, len(groups))
+		for key, groupAssets := range groups {
+			rows = append(rows, breakdownRow(names[key], groupAssets, top, weighting))
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			if rows[i].MarketCap != rows[j].MarketCap {
+				return rows[i].MarketCap > rows[j].MarketCap
+			}
+			return rows[i].Group < rows[j].Group
+		})
+
+		ctx.JSON(200, &assetBreakdown{By: by, Weighting: weighting, Quote: currency, Groups: rows, SnapshotAt: snapshotAt})
+	}
+}
+
+func breakdownRow(group string, assets []messari.Asset, top int, weighting string) assetBreakdownRow {
+	volume := 0.0
+	marketCap := 0.0
+	for _, asset := range assets {
+		volume += asset.Metrics.MarketData.VolumeLast24Hours
+		marketCap += asset.Metrics.Marketcap.CurrentMarketcapUsd
+	}
+	change := twentyFourHourChangeStats(assets, weighting)
+
+	if top > len(assets) {
+		top = len(assets)
+	}
+	constituents := make([]assetConstituent, top)
+	for i, asset := range assets[:top] {
+		constituents[i] = assetConstituent{
+			ID:                   asset.ID,
+			Symbol:               asset.Symbol,
+			Name:                 asset.Name,
+			MarketCap:            normalizeFloat(asset.Metrics.Marketcap.CurrentMarketcapUsd),
+			TwentyFourHourChange: normalizeFloat(asset.Metrics.MarketData.PercentChangeUsdLast24Hours),
+		}
+	}
+
+	return assetBreakdownRow{
+		Group:                      group,
+		AssetCount:                 len(assets),
+		MarketCap:                  normalizeFloat(marketCap),
+		Volume:                     normalizeFloat(volume),
+		MeanTwentyFourHourChange:   change.Mean,
+		MedianTwentyFourHourChange: change.Median,
+		TwentyFourHourChangeStats:  change,
+		TopConstituents:            constituents,
+	}
+}
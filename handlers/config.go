@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/config"
+)
+
+// configKey is the key of the request's *config.Config in the gin.Context
+const configKey = "config"
+
+// ConfigMiddleware func returns a middleware loading the current config from store once for the
+// request, so every middleware and handler after it sees the same config however many times it's
+// reloaded meanwhile
+func ConfigMiddleware(store *config.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(configKey, store.Config())
+		ctx.Next()
+	}
+}
+
+// configOf func returns the config ConfigMiddleware loaded for the request
+func configOf(ctx *gin.Context) *config.Config {
+	return ctx.MustGet(configKey).(*config.Config)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/config"
+)
+
+// withConfig func returns a middleware setting the request's config to what cfg returns, in place of
+// ConfigMiddleware
+func withConfig(cfg func() *config.Config) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(configKey, cfg())
+		ctx.Next()
+	}
+}
+
+// testConfig func returns the default config changed by change
+func testConfig(change func(c *config.Config)) *config.Config {
+	c := config.Default()
+	c.Messari.APIKey = "test-api-key"
+	if change != nil {
+		change(c)
+	}
+	return c
+}
+
+func TestConfigIsLoadedOncePerRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(screener bool) {
+		t.Helper()
+		b := fmt.Sprintf("messari:\n  api_key: test-api-key\nfeatures:\n  screener: %v\n", screener)
+		if err := ioutil.WriteFile(path, []byte(b), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(true)
+	args := []string{"-config", path}
+	cfg, err := config.Load(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := config.NewStore(cfg, args)
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(ConfigMiddleware(store))
+	// the config is reloaded after the request started, turning the screener off
+	reload := func(ctx *gin.Context) {
+		write(false)
+		if err := store.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		ctx.Next()
+	}
+	server.GET("/api/screener", reload, FeatureToggle(func(f config.Features) bool { return f.Screener }), func(ctx *gin.Context) {
+		if configOf(ctx) != cfg {
+			t.Error("got the reloaded config halfway through the request")
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/screener", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d, want the request to see the config it started with", rec.Code)
+	}
+	if store.Config().Features.Screener {
+		t.Fatal("the config wasn't reloaded")
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/screener", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d for the next request, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/universe"
+)
+
+// Codes of apiError, a client can switch on them rather than on the message
+const (
+	codeInvalidRequest      = "invalid_request"
+	codeUnauthorized        = "unauthorized"
+	codeForbidden           = "forbidden"
+	codeQuotaExceeded       = "quota_exceeded"
+	codeRateLimited         = "rate_limited"
+	codeNotFound            = "not_found"
+	codeAmbiguousAsset      = "ambiguous_asset"
+	codeNotReady            = "not_ready"
+	codeUpstreamError       = "upstream_error"
+	codeUpstreamUnavailable = "upstream_unavailable"
+	codeUpstreamTimeout     = "upstream_timeout"
+	codeInternal            = "internal_error"
+)
+
+// apiError struct describes why a request (or an entry of a batch) failed
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// UpstreamStatus is the status code Messari responded with when it's the cause of the error
+	UpstreamStatus int `json:"upstream_status,omitempty"`
+	// Retryable is whether the same request might succeed if it's made again later
+	Retryable bool `json:"retryable"`
+	// Candidates are the assets an ambiguous symbol could mean
+	Candidates []universe.Entry `json:"candidates,omitempty"`
+}
+
+// errorResp struct is the response json of every request which failed
+type errorResp struct {
+	apiError
+	RequestID string `json:"request_id"`
+}
+
+// respondError func aborts the request responding with status and e
+func respondError(ctx *gin.Context, status int, e apiError) {
+	ctx.AbortWithStatusJSON(status, errorResp{apiError: e, RequestID: ctx.GetString(requestIDKey)})
+}
+
+// badRequest func responds with 400 as the request is invalid for the reason message gives
+func badRequest(ctx *gin.Context, message string) {
+	respondError(ctx, http.StatusBadRequest, apiError{Code: codeInvalidRequest, Message: message})
+}
+
+// notReady func responds with 503 as the data needed to answer isn't loaded yet
+func notReady(ctx *gin.Context, message string) {
+	respondError(ctx, http.StatusServiceUnavailable, apiError{Code: codeNotReady, Message: message, Retryable: true})
+}
+
+// internalError func responds with 500 as the server failed for the reason message gives
+func internalError(ctx *gin.Context, message string) {
+	respondError(ctx, http.StatusInternalServerError, apiError{Code: codeInternal, Message: message})
+}
+
+// ambiguousAsset func responds with 300 listing the assets key could mean
+func ambiguousAsset(ctx *gin.Context, key string, candidates []universe.Entry) {
+	respondError(ctx, http.StatusMultipleChoices, ambiguousAssetError(key, candidates))
+}
+
+func ambiguousAssetError(key string, candidates []universe.Entry) apiError {
+	return apiError{
+		Code:       codeAmbiguousAsset,
+		Message:    fmt.Sprintf("%s is the symbol or slug of more than one asset, use one of the candidates' id instead.", key),
+		Candidates: candidates,
+	}
+}
+
+// upstreamError func responds with the status matching err, an error of a call to Messari made
+// for getting subject (ie. "asset btc")
+func upstreamError(ctx *gin.Context, err error, subject string) {
+	status, e := classifyUpstream(err, subject)
+	respondError(ctx, status, e)
+}
+
+// classifyUpstream func returns the status and apiError of err, an error of a call to Messari made
+// for getting subject:
+//   - 400 when subject isn't a valid symbol or slug
+//   - 404 when Messari doesn't know about subject
+//   - 504 when Messari took too long
+//   - 503 when Messari is failing (the circuit breaker is open) or rate limiting the server, or when
+//     none of the server's API keys can be used
+//   - 502 for any other error, ie. a 5xx or a network error
+func classifyUpstream(err error, subject string) (int, apiError) {
+	var apiErr *messari.APIError
+	switch {
+	case messari.IsNotFound(err):
+		return http.StatusNotFound, apiError{
+			Code:           codeNotFound,
+			Message:        fmt.Sprintf("Could not find %s.", subject),
+			UpstreamStatus: http.StatusNotFound,
+		}
+	case errors.Is(err, messari.ErrInvalidAsset):
+		return http.StatusBadRequest, apiError{
+			Code:    codeInvalidRequest,
+			Message: fmt.Sprintf("Could not get %s, it isn't a valid symbol or slug.", subject),
+		}
+	case messari.IsTimeout(err):
+		return http.StatusGatewayTimeout, apiError{
+			Code:      codeUpstreamTimeout,
+			Message:   fmt.Sprintf("Messari took too long getting %s.", subject),
+			Retryable: true,
+		}
+	case errors.Is(err, messari.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, apiError{
+			Code:      codeUpstreamUnavailable,
+			Message:   "Messari is failing, try again shortly.",
+			Retryable: true,
+		}
+	case errors.Is(err, messari.ErrNoAPIKey):
+		return http.StatusServiceUnavailable, apiError{
+			Code:      codeUpstreamUnavailable,
+			Message:   "Every Messari API key of the server is rate limited or rejected, try again shortly.",
+			Retryable: true,
+		}
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests:
+		return http.StatusServiceUnavailable, apiError{
+			Code:           codeUpstreamUnavailable,
+			Message:        fmt.Sprintf("Messari's rate limit was reached getting %s, try again shortly.", subject),
+			UpstreamStatus: apiErr.StatusCode,
+			Retryable:      true,
+		}
+	case errors.As(err, &apiErr):
+		return http.StatusBadGateway, apiError{
+			Code:           codeUpstreamError,
+			Message:        fmt.Sprintf("Messari failed getting %s.", subject),
+			UpstreamStatus: apiErr.StatusCode,
+			Retryable:      apiErr.StatusCode >= http.StatusInternalServerError,
+		}
+	}
+	return http.StatusBadGateway, apiError{
+		Code:      codeUpstreamError,
+		Message:   fmt.Sprintf("An error occured getting %s from Messari.", subject),
+		Retryable: true,
+	}
+}
+
+// NotFoundHandler func returns a handler for requests which don't match any route
+func NotFoundHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		respondError(ctx, http.StatusNotFound, apiError{Code: codeNotFound, Message: "No such route."})
+	}
+}
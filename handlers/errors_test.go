@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ultd/messari-server/messari"
+)
+
+func TestClassifyUpstream(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		code      string
+		retryable bool
+	}{
+		{name: "not found", err: fmt.Errorf("could not get asset: %w", &messari.APIError{StatusCode: http.StatusNotFound}), status: http.StatusNotFound, code: codeNotFound},
+		{name: "invalid asset", err: messari.ErrInvalidAsset, status: http.StatusBadRequest, code: codeInvalidRequest},
+		{name: "timeout", err: fmt.Errorf("could not make request: %w", context.DeadlineExceeded), status: http.StatusGatewayTimeout, code: codeUpstreamTimeout, retryable: true},
+		{name: "no API key", err: fmt.Errorf("could not make request: %w", messari.ErrNoAPIKey), status: http.StatusServiceUnavailable, code: codeUpstreamUnavailable, retryable: true},
+		{name: "circuit open", err: fmt.Errorf("could not make request: %w", messari.ErrCircuitOpen), status: http.StatusServiceUnavailable, code: codeUpstreamUnavailable, retryable: true},
+		{name: "rate limited", err: &messari.APIError{StatusCode: http.StatusTooManyRequests}, status: http.StatusServiceUnavailable, code: codeUpstreamUnavailable, retryable: true},
+		{name: "server error", err: &messari.APIError{StatusCode: http.StatusInternalServerError}, status: http.StatusBadGateway, code: codeUpstreamError, retryable: true},
+		{name: "client error", err: &messari.APIError{StatusCode: http.StatusBadRequest}, status: http.StatusBadGateway, code: codeUpstreamError},
+		{name: "other error", err: errors.New("connection reset"), status: http.StatusBadGateway, code: codeUpstreamError, retryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, apiErr := classifyUpstream(tt.err, "btc")
+			if status != tt.status || apiErr.Code != tt.code || apiErr.Retryable != tt.retryable {
+				t.Errorf("got %d %s (retryable: %v), want %d %s (retryable: %v)", status, apiErr.Code, apiErr.Retryable, tt.status, tt.code, tt.retryable)
+			}
+		})
+	}
+}
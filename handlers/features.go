@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/config"
+)
+
+// FeatureToggle func returns a middleware which responds with 404 while enabled returns false for the
+// features of the request's config (see ConfigMiddleware), so an optional route can be turned off
+// without restarting the server
+func FeatureToggle(enabled func(config.Features) bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !enabled(configOf(ctx).Features) {
+			respondError(ctx, http.StatusNotFound, apiError{Code: codeNotFound, Message: "This route is disabled."})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/filter"
+	"github.com/ultd/messari-server/logging"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/quote"
+	"github.com/ultd/messari-server/universe"
+)
+
+// defaultMinMarketCap is the market cap assets need to be included in aggregates when
+// they're not otherwise filtered
+const defaultMinMarketCap = 20000000
+
+// queryFilter func parses the "filter" query param. Responds with 400 and returns false if
+// it's not a valid filter expression, returns a nil Filter if it wasn't specified.
+func queryFilter(ctx *gin.Context, param string) (*filter.Filter, bool) {
+	src := ctx.Query(param)
+	if src == "" {
+		return nil, true
+	}
+	f, err := filter.Parse(src)
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf("Invalid %s specified in query: %v.", param, err))
+		return nil, false
+	}
+	return f, true
+}
+
+// aggregateFilter func returns the Filter of the assets to aggregate. Besides the "filter" query param,
+// the "tags" and "sector" query params match assets having the given tag and sector among their normalized
+// taxonomy terms, the same GetTaxonomyHandler lists, and "min_marketcap" sets the market cap assets need,
+// which defaults to defaultMinMarketCap when nothing else is filtered on.
+func aggregateFilter(ctx *gin.Context) (*filter.Filter, bool) {
+	f, ok := queryFilter(ctx, "filter")
+	if !ok {
+		return nil, false
+	}
+	tags := ctx.Query("tags")
+	sector := ctx.Query("sector")
+
+	filters := []*filter.Filter{f}
+	if tags != "" {
+		tagsFilter, err := filter.Equals("tags", tags)
+		if err != nil {
+			badRequest(ctx, "Invalid tags specified in query.")
+			return nil, false
+		}
+		filters = append(filters, tagsFilter)
+	}
+	if sector != "" {
+		sectorFilter, err := filter.Equals("sectors", sector)
+		if err != nil {
+			badRequest(ctx, "Invalid sector specified in query.")
+			return nil, false
+		}
+		filters = append(filters, sectorFilter)
+	}
+
+	minMarketCap := 0.0
+	if f == nil && tags == "" && sector == "" {
+		minMarketCap = defaultMinMarketCap
+	}
+	minMarketCap, ok = queryFloat(ctx, "min_marketcap", minMarketCap)
+	if !ok {
+		return nil, false
+	}
+	if minMarketCap > 0 {
+		floor, err := filter.Parse(fmt.Sprintf("marketcap >= %v", minMarketCap))
+		if err != nil {
+			badRequest(ctx, "Invalid min_marketcap specified in query.")
+			return nil, false
+		}
+		filters = append(filters, floor)
+	}
+
+	return filter.And(filters...), true
+}
+
+const (
+	defaultAssetsLimit = 20
+	maxAssetsLimit     = 500
+)
+
+// filteredAssets func responds with the page of assets in snapshot matching f, quoted in currency and
+// projected to fields
+func filteredAssets(ctx *gin.Context, snapshot *universe.Snapshot, f *filter.Filter, fields []string, fx *quote.Table, currency string) {
+	page, limit := 1, defaultAssetsLimit
+	if v := ctx.Query("page"); v != "" {
+		pg, err := strconv.Atoi(v)
+		if err != nil || pg < 1 {
+			badRequest(ctx, "Invalid page specified in query.")
+			return
+		}
+		page = pg
+	}
+	if v := ctx.Query("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxAssetsLimit {
+			badRequest(ctx, "Invalid limit specified in query.")
+			return
+		}
+		limit = l
+	}
+
+	assets, snapshotAt := snapshot.Assets()
+	if snapshotAt.IsZero() {
+		notReady(ctx, "Asset snapshot is still loading, try again shortly.")
+		return
+	}
+
+	skip := (page - 1) * limit
+	matched := make([]messari.Asset, 0, limit)
+	for _, asset := range assets {
+		if len(matched) == limit {
+			break
+		}
+		if !f.Match(asset) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		matched = append(matched, asset)
+	}
+	matched, ok := quoteAssets(ctx, fx, currency, matched, assets)
+	if !ok {
+		return
+	}
+
+	data := make([]interface{}, 0, len(matched))
+	for _, asset := range matched {
+		if fields == nil {
+			data = append(data, asset)
+			continue
+		}
+		projected, err := messari.Project(asset, fields)
+		if err != nil {
+			logging.From(ctx.Request.Context()).Error(err)
+			internalError(ctx, "An error occured selecting fields of assets.")
+			return
+		}
+		data = append(data, projected)
+	}
+	ctx.JSON(200, data)
+}
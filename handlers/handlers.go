@@ -3,87 +3,225 @@ package handlers
 import (
 	"fmt"
 	"math"
-	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
+	"github.com/ultd/messari-server/logging"
 	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/quote"
+	"github.com/ultd/messari-server/universe"
 )
 
-// GetAllAssetsHandler func is a gin route controller for handling assets
-func GetAllAssetsHandler(apiKey string) gin.HandlerFunc {
+// GetAllAssetsHandler func is a gin route controller for handling assets. When a filter expression is
+// specified the assets are filtered from snapshot instead of being fetched from Messari.
+func GetAllAssetsHandler(m *messari.Client, snapshot *universe.Snapshot, fx *quote.Table) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		page := ctx.Query("page")
-		m := messari.New(apiKey)
-		var opts *messari.GetAllAssetsOptions
+		fields, ok := queryFields(ctx, messari.AssetSchema)
+		if !ok {
+			return
+		}
+		f, ok := queryFilter(ctx, "filter")
+		if !ok {
+			return
+		}
+		currency, ok := queryQuote(ctx, fx)
+		if !ok {
+			return
+		}
+		if f != nil {
+			if passthrough(ctx) {
+				badRequest(ctx, "Filtered assets are answered from the server's cache, the filter can't be used with the X-Messari-Api-Key header.")
+				return
+			}
+			filteredAssets(ctx, snapshot, f, fields, fx, currency)
+			return
+		}
+		opts := &messari.GetAllAssetsOptions{
+			Fields: fields,
+		}
 		if page != "" {
 			pg, err := strconv.Atoi(page)
 			if err != nil {
-				ctx.JSON(400, gin.H{"message": "Invalid page specified in query."})
+				badRequest(ctx, "Invalid page specified in query.")
 				return
 			}
-			opts = &messari.GetAllAssetsOptions{
-				Page: intPtr(pg),
-			}
+			opts.Page = intPtr(pg)
 		}
 
-		resp, err := m.GetAllAssets(opts)
+		resp, err := m.GetAllAssets(ctx.Request.Context(), opts)
 		if err != nil {
-			logrus.Error(err)
-			ctx.JSON(400, gin.H{"message": "An error occured getting all asset metrics."})
+			logging.From(ctx.Request.Context()).Error(err)
+			upstreamError(ctx, err, "assets")
 			return
 		}
-		ctx.JSON(200, resp.Data)
+		// the snapshot is only looked at for the prices of BTC and ETH, so USD requests aren't counted as its lookups
+		var all []messari.Asset
+		if currency != quote.USD {
+			all, _ = snapshot.Assets()
+		}
+		data, ok := quoteAssets(ctx, fx, currency, resp.Data, all)
+		if !ok {
+			return
+		}
+		ctx.JSON(200, data)
 	}
 }
 
 // GetAssetHandler func returns a hanlder for getting metadata of an asset using a symbol or a slug
-func GetAssetHandler(apiKey string) gin.HandlerFunc {
+func GetAssetHandler(m *messari.Client) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		symbolOrSlug := ctx.Param("symbolOrSlug")
 		if symbolOrSlug == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": "No slug or symbol provided in URL."})
+			badRequest(ctx, "No slug or symbol provided in URL.")
 			return
 		}
-		m := messari.New(apiKey)
-		resp, err := m.GetAsset(symbolOrSlug, &messari.GetAssetOptions{
-			// have to put all fields in single string comma seperated as API dictates
-			// Fields: []string{"symbol,name"},
-			Fields: nil,
+		fields, ok := queryFields(ctx, messari.AssetMetaDataSchema)
+		if !ok {
+			return
+		}
+		resp, err := m.GetAsset(ctx.Request.Context(), symbolOrSlug, &messari.GetAssetOptions{
+			Fields: fields,
 		})
 		if err != nil {
-			logrus.Error(err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("An error occured getting %s's metrics.", symbolOrSlug)})
+			logging.From(ctx.Request.Context()).Error(err)
+			upstreamError(ctx, err, fmt.Sprintf("asset %s", symbolOrSlug))
 			return
 		}
 		ctx.JSON(200, resp.Data)
 	}
 }
 
-// GetAssetMetricsHandler func returns a hanlder for getting metrics of an asset using a symbol or a slug
-func GetAssetMetricsHandler(apiKey string) gin.HandlerFunc {
+// GetAssetMetricsHandler func returns a hanlder for getting metrics of an asset using a symbol or a slug.
+// The symbol or slug is resolved using dir first so ambiguous symbols aren't silently passed on to Messari.
+func GetAssetMetricsHandler(m *messari.Client, dir *universe.Directory, fx *quote.Table) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		symbolOrSlug := ctx.Param("symbolOrSlug")
 		if symbolOrSlug == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": "No slug or symbol provided in URL."})
+			badRequest(ctx, "No slug or symbol provided in URL.")
+			return
+		}
+		fields, ok := queryFields(ctx, messari.AssetMetricsSchema)
+		if !ok {
+			return
+		}
+		currency, ok := queryQuote(ctx, fx)
+		if !ok {
 			return
 		}
-		m := messari.New(apiKey)
-		resp, err := m.GetAssetMetrics(symbolOrSlug, &messari.GetAssetMetricsOptions{
-			// have to put all fields in single string comma seperated as API dictates
-			// Fields: []string{"symbol,name"},
-			Fields: nil,
+		key, err := resolveAsset(dir, symbolOrSlug)
+		if ambiguous, ok := err.(*universe.AmbiguousError); ok {
+			ambiguousAsset(ctx, symbolOrSlug, ambiguous.Candidates)
+			return
+		}
+		// the prices quoting needs are requested even when they're not among fields
+		requested, project := quoteFields(fields, currency)
+		resp, err := m.GetAssetMetrics(ctx.Request.Context(), key, &messari.GetAssetMetricsOptions{
+			Fields: requested,
 		})
 		if err != nil {
-			logrus.Error(err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("An error occured getting %s's metrics.", symbolOrSlug)})
+			logging.From(ctx.Request.Context()).Error(err)
+			upstreamError(ctx, err, fmt.Sprintf("asset %s", symbolOrSlug))
 			return
 		}
-		ctx.JSON(200, resp.Data)
+		resp.Data.Metrics, ok = quoteMetrics(ctx, fx, currency, resp.Data.Metrics)
+		if !ok {
+			return
+		}
+		if !project {
+			ctx.JSON(200, resp.Data)
+			return
+		}
+		projected, err := messari.Project(resp.Data, fields)
+		if err != nil {
+			logging.From(ctx.Request.Context()).Error(err)
+			internalError(ctx, fmt.Sprintf("An error occured selecting fields of asset %s.", symbolOrSlug))
+			return
+		}
+		ctx.JSON(200, projected)
 	}
 }
 
+const maxBatchSize = 100
+
+// assetsBatchRequest struct is the request json of GetAssetsMetricsBatchHandler
+type assetsBatchRequest struct {
+	Assets []string `json:"assets" binding:"required"`
+}
+
+// assetsBatchResult struct is a single asset's entry in the response json of GetAssetsMetricsBatchHandler
+type assetsBatchResult struct {
+	Asset string                        `json:"asset"`
+	Data  *messari.AssetMetricsMetadata `json:"data,omitempty"`
+	// Error is why the asset's metrics couldn't be got, in the same format as a failed request's
+	Error *apiError `json:"error,omitempty"`
+}
+
+// GetAssetsMetricsBatchHandler func returns a handler for getting metrics of a list of assets
+// using their symbols or slugs
+func GetAssetsMetricsBatchHandler(m *messari.Client, dir *universe.Directory) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req assetsBatchRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			badRequest(ctx, "Invalid body, expected a list of symbols or slugs in assets.")
+			return
+		}
+		if len(req.Assets) > maxBatchSize {
+			badRequest(ctx, fmt.Sprintf("No more than %d assets can be requested at once.", maxBatchSize))
+			return
+		}
+		fields, ok := queryFields(ctx, messari.AssetMetricsSchema)
+		if !ok {
+			return
+		}
+
+		batch := make([]assetsBatchResult, len(req.Assets))
+		keys := make([]string, 0, len(req.Assets))
+		// index in batch of each of keys
+		indexes := make([]int, 0, len(req.Assets))
+		for i, symbolOrSlug := range req.Assets {
+			batch[i].Asset = symbolOrSlug
+			if !validAssetKey(symbolOrSlug) {
+				batch[i].Error = &apiError{
+					Code:    codeInvalidRequest,
+					Message: fmt.Sprintf("%q isn't a symbol or slug.", symbolOrSlug),
+				}
+				continue
+			}
+			key, err := resolveAsset(dir, symbolOrSlug)
+			if ambiguous, ok := err.(*universe.AmbiguousError); ok {
+				e := ambiguousAssetError(symbolOrSlug, ambiguous.Candidates)
+				batch[i].Error = &e
+				continue
+			}
+			keys = append(keys, key)
+			indexes = append(indexes, i)
+		}
+
+		results := m.GetAssetsMetricsBatch(ctx.Request.Context(), keys, &messari.GetAssetMetricsOptions{
+			Fields: fields,
+		})
+		for i, result := range results {
+			entry := &batch[indexes[i]]
+			entry.Data = result.Data
+			if result.Err != nil {
+				logging.From(ctx.Request.Context()).Error(result.Err)
+				_, e := classifyUpstream(result.Err, fmt.Sprintf("asset %s", entry.Asset))
+				entry.Error = &e
+			}
+		}
+		ctx.JSON(200, batch)
+	}
+}
+
+// validAssetKey func returns whether key can be a symbol or slug, which can't hold anything making it
+// more than a single segment of a path of Messari's API
+func validAssetKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, "/?#") && !strings.Contains(key, "..")
+}
+
 // AssetAggregateMetrics struct is the response json of GetAssetMetricsAggregateHandler
 type assetAggregateMetrics struct {
 	Tags                 []string `json:"tags,omitempty"`
@@ -91,83 +229,56 @@ type assetAggregateMetrics struct {
 	Volume               float64  `json:"volume,omitempty"`
 	TwentyFourHourChange float64  `json:"24HourChange,omitempty"`
 	MarketCap            float64  `json:"marketcap,omitempty"`
+	// TwentyFourHourChangeStats holds the 24 hour change's statistics, TwentyFourHourChange is its WeightedMean
+	TwentyFourHourChangeStats changeStats `json:"24HourChangeStats"`
+	// Quote is the currency Volume and MarketCap are in
+	Quote      string    `json:"quote"`
+	SnapshotAt time.Time `json:"snapshotAt"`
 }
 
-// GetAssetMetricsAggregateHandler func returns a hanlder for getting metrics of an asset using a symbol or a slug
-func GetAssetMetricsAggregateHandler(apiKey string) gin.HandlerFunc {
+// GetAssetMetricsAggregateHandler func returns a hanlder for getting the aggregated metrics of every asset,
+// optionally filtered by a filter expression, tags and sector. It answers from snapshot rather than
+// fetching every asset.
+func GetAssetMetricsAggregateHandler(snapshot *universe.Snapshot, fx *quote.Table) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		symbolOrSlug := ctx.Param("symbolOrSlug")
-
-		tags := ctx.Query("tags")
-		sector := ctx.Query("sector")
-
-		m := messari.New(apiKey)
-
-		page := 1
+		f, ok := aggregateFilter(ctx)
+		if !ok {
+			return
+		}
+		weighting, ok := queryWeighting(ctx)
+		if !ok {
+			return
+		}
+		currency, ok := queryQuote(ctx, fx)
+		if !ok {
+			return
+		}
 
-		var assetMetricsAggregate []messari.Asset = make([]messari.Asset, 0, 500)
-		var resp *messari.GetAllAssetsResp
-		var err error
-		for {
-			if resp != nil && resp.Data == nil {
-				break
-			}
-			resp, err = m.GetAllAssets(&messari.GetAllAssetsOptions{
-				Page:             &page,
-				Limit:            intPtr(500),
-				Fields:           []string{"id,name,symbol,slug,metrics,profile/general/overview/tags,profile/general/overview/sector"},
-				WithMertricsOnly: boolPtr(true),
-				WithProfilesOnly: boolPtr(true),
-			})
-			if err != nil {
-				logrus.Error(err)
-				ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("An error occured getting %s's metrics.", symbolOrSlug)})
-				return
-			}
-			for _, asset := range resp.Data {
-				// if getting all asset metrics (not filtered by tags or sector), only return assets with
-				// greater than 20,000,000 market cap.
-				if tags == "" && sector == "" && asset.Metrics.Marketcap.CurrentMarketcapUsd < 20000000 {
-					break
-				}
+		assets, snapshotAt := snapshot.Assets()
+		if snapshotAt.IsZero() {
+			notReady(ctx, "Asset snapshot is still loading, try again shortly.")
+			return
+		}
 
-				// if only tags specified in query
-				if tags != "" && sector == "" {
-					if asset.Profile.General.Overview.Tags != nil && *asset.Profile.General.Overview.Tags == tags {
-						assetMetricsAggregate = append(assetMetricsAggregate, asset)
-					}
-
-					// if only sector specified in query
-				} else if tags == "" && sector != "" {
-					if asset.Profile.General.Overview.Sector != nil && *asset.Profile.General.Overview.Sector == sector {
-						assetMetricsAggregate = append(assetMetricsAggregate, asset)
-					}
-
-					// if tags & sector specified in query
-				} else if tags != "" && sector != "" {
-					if asset.Profile.General.Overview.Sector != nil && *asset.Profile.General.Overview.Sector == sector &&
-						asset.Profile.General.Overview.Tags != nil && *asset.Profile.General.Overview.Tags == tags {
-						assetMetricsAggregate = append(assetMetricsAggregate, asset)
-					}
-
-					// if tags & sector NOT specified in query, append as normal
-				} else {
-					assetMetricsAggregate = append(assetMetricsAggregate, asset)
-				}
+		assetMetricsAggregate := make([]messari.Asset, 0, 500)
+		for _, asset := range assets {
+			if f.Match(asset) {
+				assetMetricsAggregate = append(assetMetricsAggregate, asset)
 			}
-			page++
+		}
+		assetMetricsAggregate, ok = quoteAssets(ctx, fx, currency, assetMetricsAggregate, assets)
+		if !ok {
+			return
 		}
 
 		allTags := []string{}
 		allSectors := []string{}
 		volume := 0.0
 		marketCap := 0.0
-		twentyFourHourChangeAgg := 0.0
 
 		for _, asset := range assetMetricsAggregate {
 			volume += asset.Metrics.MarketData.VolumeLast24Hours
 			marketCap += asset.Metrics.Marketcap.CurrentMarketcapUsd
-			twentyFourHourChangeAgg += asset.Metrics.MarketData.PercentChangeUsdLast24Hours
 			if asset.Profile.General.Overview.Tags != nil &&
 				*asset.Profile.General.Overview.Tags != "" &&
 				!includesString(allTags, *asset.Profile.General.Overview.Tags) {
@@ -180,18 +291,48 @@ func GetAssetMetricsAggregateHandler(apiKey string) gin.HandlerFunc {
 			}
 		}
 
+		change := twentyFourHourChangeStats(assetMetricsAggregate, weighting)
 		agg := &assetAggregateMetrics{
-			Tags:                 allTags,
-			Sector:               allSectors,
-			Volume:               normalizeFloat(volume),
-			MarketCap:            normalizeFloat(marketCap),
-			TwentyFourHourChange: normalizeFloat(twentyFourHourChangeAgg / float64(len(assetMetricsAggregate))),
+			Tags:                      allTags,
+			Sector:                    allSectors,
+			Volume:                    normalizeFloat(volume),
+			MarketCap:                 normalizeFloat(marketCap),
+			TwentyFourHourChange:      change.WeightedMean,
+			TwentyFourHourChangeStats: change,
+			Quote:                     currency,
+			SnapshotAt:                snapshotAt,
 		}
 
 		ctx.JSON(200, agg)
 	}
 }
 
+// queryFields func parses the "fields" query param against schema. Responds with 400 and
+// returns false if any of the fields are invalid.
+func queryFields(ctx *gin.Context, schema messari.FieldSchema) ([]string, bool) {
+	fields, err := messari.ParseFields(schema, ctx.Query("fields"))
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf("Invalid fields specified in query: %v.", err))
+		return nil, false
+	}
+	return fields, true
+}
+
+// resolveAsset func resolves symbolOrSlug to an asset ID using dir. Returns symbolOrSlug as is when
+// dir doesn't know about the asset (ie. it hasn't been filled yet, or the asset has no metrics or profile
+// so it's not in the snapshot dir is built from) so Messari can resolve it instead.
+// The error is an *universe.AmbiguousError if symbolOrSlug matches several assets.
+func resolveAsset(dir *universe.Directory, symbolOrSlug string) (string, error) {
+	entry, err := dir.Resolve(symbolOrSlug)
+	if err == universe.ErrNotFound {
+		return symbolOrSlug, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return entry.ID, nil
+}
+
 func intPtr(v int) *int {
 	return &v
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/universe"
+)
+
+func TestAssetsBatchRejectsPaths(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.EscapedPath())
+		mu.Unlock()
+		w.Write([]byte(`{"data": {"symbol": "BTC"}}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	m := messari.New("test-api-key", messari.WithBaseURL(u), messari.WithRateLimit(6000, 100))
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.POST("/api/assets/batch", GetAssetsMetricsBatchHandler(m, universe.NewDirectory()))
+
+	body := `{"assets": ["btc", "../../../v2/assets?x=", "a/b", "a?b", "a#b", "..", "a..b"]}`
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/assets/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var batch []assetsBatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 7 {
+		t.Fatalf("got %d entries, want 7", len(batch))
+	}
+	if batch[0].Error != nil || batch[0].Data == nil {
+		t.Errorf("got error %+v for btc, want its metrics", batch[0].Error)
+	}
+	for _, entry := range batch[1:] {
+		if entry.Error == nil || entry.Error.Code != codeInvalidRequest {
+			t.Errorf("got error %+v for %q, want %s", entry.Error, entry.Asset, codeInvalidRequest)
+		}
+	}
+	if len(paths) != 1 || paths[0] != "/api/v1/assets/btc/metrics" {
+		t.Errorf("got requests to %v, want only btc's metrics", paths)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+)
+
+// Check struct is a named condition the server has to meet to be ready for traffic
+type Check struct {
+	Name string
+	// Check returns why the condition isn't met, nil if it is
+	Check func() error
+}
+
+// WarmedCheck func returns a Check which passes once a cache has been filled, updatedAt being when it
+// last was (zero if never)
+func WarmedCheck(name string, updatedAt func() time.Time) Check {
+	return Check{Name: name, Check: func() error {
+		if updatedAt().IsZero() {
+			return fmt.Errorf("%s hasn't been loaded yet", name)
+		}
+		return nil
+	}}
+}
+
+// BreakerCheck func returns a Check which fails while m's circuit breaker is open. It passes once the
+// breaker is half-open, otherwise no traffic would come for its trial request to close it.
+func BreakerCheck(m *messari.Client) Check {
+	return Check{Name: "messari", Check: func() error {
+		if state := m.BreakerState(); state == messari.BreakerOpen {
+			return fmt.Errorf("circuit breaker is %s", state)
+		}
+		return nil
+	}}
+}
+
+// HealthzHandler func returns a handler which responds with 200 as long as the process is serving requests
+func HealthzHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// ReadyzHandler func returns a handler which responds with 200 when all of checks pass, otherwise
+// with 503 and the reason each failing check gave
+func ReadyzHandler(checks ...Check) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		results := make(map[string]string, len(checks))
+		ready := true
+		for _, c := range checks {
+			if err := c.Check(); err != nil {
+				results[c.Name] = err.Error()
+				ready = false
+				continue
+			}
+			results[c.Name] = "ok"
+		}
+		if !ready {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": results})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "ready", "checks": results})
+	}
+}
+
+// Cache struct names a locally kept cache for StatusHandler
+type Cache struct {
+	Name      string
+	UpdatedAt func() time.Time
+}
+
+// statusResp struct is the response json of StatusHandler
+type statusResp struct {
+	Upstream messari.ClientStatus  `json:"upstream"`
+	Caches   map[string]cacheState `json:"caches"`
+	Time     time.Time             `json:"time"`
+}
+
+// cacheState struct is a single cache's entry in statusResp
+type cacheState struct {
+	UpdatedAt  *time.Time `json:"updatedAt"`
+	AgeSeconds *float64   `json:"ageSeconds"`
+}
+
+// StatusHandler func returns a handler reporting how requests against Messari's API have been going
+// (latency percentiles, last successful call per endpoint, remaining rate limit budget) and how old
+// each of caches is
+func StatusHandler(m *messari.Client, caches ...Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		now := time.Now()
+		resp := statusResp{
+			Upstream: m.Status(),
+			Caches:   make(map[string]cacheState, len(caches)),
+			Time:     now,
+		}
+		for _, c := range caches {
+			var state cacheState
+			if updatedAt := c.UpdatedAt(); !updatedAt.IsZero() {
+				age := now.Sub(updatedAt).Seconds()
+				state = cacheState{UpdatedAt: &updatedAt, AgeSeconds: &age}
+			}
+			resp.Caches[c.Name] = state
+		}
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
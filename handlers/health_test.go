@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ultd/messari-server/messari"
+)
+
+func TestBreakerCheckFailsOnlyWhileOpen(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	cooldown := 50 * time.Millisecond
+	m := messari.New("test-api-key", messari.WithBaseURL(u), messari.WithRateLimit(6000, 100), messari.WithCircuitBreaker(1, cooldown))
+	check := BreakerCheck(m)
+
+	if err := check.Check(); err != nil {
+		t.Fatalf("got %v while closed, want nil", err)
+	}
+	m.GetAssetMetrics(context.Background(), "btc", nil)
+	if m.BreakerState() != messari.BreakerOpen {
+		t.Fatalf("breaker is %s after a failed request, want open", m.BreakerState())
+	}
+	if err := check.Check(); err == nil {
+		t.Error("got nil while open, want an error")
+	}
+
+	time.Sleep(cooldown)
+	if m.BreakerState() != messari.BreakerHalfOpen {
+		t.Fatalf("breaker is %s after its cooldown, want half-open", m.BreakerState())
+	}
+	if err := check.Check(); err != nil {
+		t.Errorf("got %v while half-open, want nil", err)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/ultd/messari-server/logging"
+)
+
+// requestIDHeader is the header carrying a request's ID, both in the request and in its response
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey is the key of the request's ID in the gin.Context
+const requestIDKey = "request_id"
+
+// validRequestID matches request IDs from incoming headers which are safe to log and echo back
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIDMiddleware func returns a middleware giving each request an ID, the incoming X-Request-ID
+// header if it's valid, otherwise a new one. The ID is returned in the X-Request-ID header and the
+// request's logger (see logging.From) adds it to every line.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		ctx.Header(requestIDHeader, id)
+		ctx.Set(requestIDKey, id)
+		entry := logrus.WithField("request_id", id)
+		ctx.Request = ctx.Request.WithContext(logging.WithLogger(ctx.Request.Context(), entry))
+		ctx.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// AccessLogMiddleware func returns a middleware logging a line for each request once it's handled.
+// The query isn't logged as is since it may hold secrets.
+func AccessLogMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		entry := logging.From(ctx.Request.Context()).WithFields(logrus.Fields{
+			"method":     ctx.Request.Method,
+			"route":      ctx.FullPath(),
+			"path":       ctx.Request.URL.Path,
+			"status":     status,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":      ctx.Writer.Size(),
+			"client_ip":  clientIP(ctx),
+			"user_agent": ctx.Request.UserAgent(),
+		})
+		if len(ctx.Errors) > 0 {
+			entry = entry.WithField("errors", ctx.Errors.String())
+		}
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Error("request handled")
+		case status >= http.StatusBadRequest:
+			entry.Warn("request handled")
+		default:
+			entry.Info("request handled")
+		}
+	}
+}
+
+// RecoveryMiddleware func returns a middleware responding with 500 when a handler panics, logging the
+// panic and its stack with the request's logger
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.From(ctx.Request.Context()).WithFields(logrus.Fields{
+					"panic": r,
+					"stack": string(debug.Stack()),
+				}).Error("handler panicked")
+				internalError(ctx, "An internal error occured.")
+			}
+		}()
+		ctx.Next()
+	}
+}
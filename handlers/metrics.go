@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/metrics"
+)
+
+// MetricsMiddleware func returns a middleware recording every request in metrics by its route
+// pattern, so "/api/asset/btc" and "/api/asset/eth" are both counted as "/api/asset/:symbolOrSlug"
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		route := ctx.FullPath()
+		if route == "" {
+			// the request didn't match any route, its path isn't used to keep the labels bounded
+			route = "unmatched"
+		}
+		metrics.ObserveRequest(route, ctx.Request.Method, ctx.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/universe"
+)
+
+const (
+	defaultMoversLimit        = 10
+	maxMoversLimit            = 100
+	defaultMoversMinMarketCap = 10000000
+	defaultMoversMinVolume    = 1000000
+)
+
+// moverWindows maps the "window" query param of MoversHandler to a func returning an asset's percent
+// change over that window
+var moverWindows = map[string]func(messari.Asset) (float64, bool){
+	"1h": func(asset messari.Asset) (float64, bool) {
+		if asset.Metrics.MarketData.PercentChangeUsdLast1Hour == nil {
+			return 0, false
+		}
+		return *asset.Metrics.MarketData.PercentChangeUsdLast1Hour, true
+	},
+	"24h": func(asset messari.Asset) (float64, bool) {
+		return asset.Metrics.MarketData.PercentChangeUsdLast24Hours, true
+	},
+}
+
+// moversResp struct is the response json of MoversHandler
+type moversResp struct {
+	Window       string     `json:"window"`
+	Gainers      []moverRow `json:"gainers"`
+	Losers       []moverRow `json:"losers"`
+	VolumeSpikes []moverRow `json:"volumeSpikes"`
+	SnapshotAt   time.Time  `json:"snapshotAt"`
+}
+
+// moverRow struct is a single asset in one of the lists of moversResp
+type moverRow struct {
+	ID        string  `json:"id"`
+	Symbol    string  `json:"symbol"`
+	Name      string  `json:"name"`
+	Slug      string  `json:"slug"`
+	Price     float64 `json:"price"`
+	MarketCap float64 `json:"marketcap"`
+	Volume    float64 `json:"volume"`
+	Change    float64 `json:"change"`
+	// VolumeRatio is the asset's 24 hour volume relative to its rolling baseline, 0 if there's no baseline yet
+	VolumeRatio float64 `json:"volumeRatio,omitempty"`
+}
+
+// MoversHandler func returns a handler for getting the assets which gained or lost the most over a window
+// (1h or 24h) and the ones whose volume is the highest relative to their rolling baseline. Assets below
+// min_marketcap or min_volume are left out so illiquid assets don't dominate, and the assets considered
+// can be narrowed down with a filter expression in universe.
+func MoversHandler(snapshot *universe.Snapshot) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		window := ctx.DefaultQuery("window", "24h")
+		changeOf, ok := moverWindows[window]
+		if !ok {
+			badRequest(ctx, "Invalid window specified in query, expected 1h or 24h.")
+			return
+		}
+		f, ok := queryFilter(ctx, "universe")
+		if !ok {
+			return
+		}
+		limit := defaultMoversLimit
+		if l := ctx.Query("limit"); l != "" {
+			v, err := strconv.Atoi(l)
+			if err != nil || v < 1 || v > maxMoversLimit {
+				badRequest(ctx, "Invalid limit specified in query.")
+				return
+			}
+			limit = v
+		}
+		minMarketCap, ok := queryFloat(ctx, "min_marketcap", defaultMoversMinMarketCap)
+		if !ok {
+			return
+		}
+		minVolume, ok := queryFloat(ctx, "min_volume", defaultMoversMinVolume)
+		if !ok {
+			return
+		}
+
+		assets, snapshotAt := snapshot.Assets()
+		if snapshotAt.IsZero() {
+			notReady(ctx, "Asset snapshot is still loading, try again shortly.")
+			return
+		}
+
+		var movers, spikes []moverRow
+		for _, asset := range assets {
+			if asset.Metrics.Marketcap.CurrentMarketcapUsd < minMarketCap ||
+				asset.Metrics.MarketData.VolumeLast24Hours < minVolume ||
+				!f.Match(asset) {
+				continue
+			}
+			change, ok := changeOf(asset)
+			if !ok {
+				continue
+			}
+			row := moverRow{
+				ID:        asset.ID,
+				Symbol:    asset.Symbol,
+				Name:      asset.Name,
+				Slug:      asset.Slug,
+				Price:     asset.Metrics.MarketData.PriceUsd,
+				MarketCap: normalizeFloat(asset.Metrics.Marketcap.CurrentMarketcapUsd),
+				Volume:    normalizeFloat(asset.Metrics.MarketData.VolumeLast24Hours),
+				Change:    normalizeFloat(change),
+			}
+			if baseline, ok := snapshot.VolumeBaseline(asset.ID); ok && baseline > 0 {
+				row.VolumeRatio = normalizeFloat(asset.Metrics.MarketData.VolumeLast24Hours / baseline)
+				spikes = append(spikes, row)
+			}
+			movers = append(movers, row)
+		}
+
+		sort.SliceStable(movers, func(i, j int) bool { return movers[i].Change > movers[j].Change })
+		gainers := topRows(movers, limit, func(row moverRow) bool { return row.Change > 0 })
+		losers := make([]moverRow, 0, limit)
+		for i := len(movers) - 1; i >= 0 && len(losers) < limit; i-- {
+			if movers[i].Change < 0 {
+				losers = append(losers, movers[i])
+			}
+		}
+		sort.SliceStable(spikes, func(i, j int) bool { return spikes[i].VolumeRatio > spikes[j].VolumeRatio })
+		volumeSpikes := topRows(spikes, limit, func(row moverRow) bool { return row.VolumeRatio > 1 })
+
+		ctx.JSON(200, &moversResp{
+			Window:       window,
+			Gainers:      gainers,
+			Losers:       losers,
+			VolumeSpikes: volumeSpikes,
+			SnapshotAt:   snapshotAt,
+		})
+	}
+}
+
+// topRows func returns up to limit of the first rows which pass keep
+func topRows(rows []moverRow, limit int, keep func(moverRow) bool) []moverRow {
+	top := make([]moverRow, 0, limit)
+	for _, row := range rows {
+		if len(top) == limit {
+			break
+		}
+		if keep(row) {
+			top = append(top, row)
+		}
+	}
+	return top
+}
+
+// queryFloat func parses the query param named key as a float, returning def if it's not specified.
+// Responds with 400 and returns false if it's not a number.
+func queryFloat(ctx *gin.Context, key string, def float64) (float64, bool) {
+	v := ctx.Query(key)
+	if v == "" {
+		return def, true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		badRequest(ctx, "Invalid "+key+" specified in query.")
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/logging"
+	"github.com/ultd/messari-server/messari"
+)
+
+// messariKeyHeader is the header a request can carry its caller's own Messari API key in
+const messariKeyHeader = "X-Messari-Api-Key"
+
+// passthroughKey is set in the gin.Context of requests whose calls to Messari are made with their
+// caller's own API key
+const passthroughKey = "messari_passthrough"
+
+// PassthroughMiddleware func returns a middleware making the calls to Messari of requests carrying the
+// X-Messari-Api-Key header with that key rather than the server's (see messari.WithCallerKey), when
+// the request's config (see ConfigMiddleware) enables it. The header is taken off the request so the key can't end up in logs or traces.
+//
+// Only routes (ie. "/api/asset/:symbolOrSlug") call Messari for each request, the others are answered
+// from copies of Messari's data the server keeps with its own keys. Nothing about those copies is
+// accounted per caller key, so requests carrying the header to other routes are refused rather than
+// answered with data the caller's plan didn't pay for.
+func PassthroughMiddleware(routes ...string) gin.HandlerFunc {
+	direct := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		direct[route] = true
+	}
+	return func(ctx *gin.Context) {
+		apiKey := ctx.GetHeader(messariKeyHeader)
+		if apiKey == "" {
+			ctx.Next()
+			return
+		}
+		ctx.Request.Header.Del(messariKeyHeader)
+		if !configOf(ctx).Messari.Passthrough.Enabled {
+			badRequest(ctx, "Passing your own Messari API key in the X-Messari-Api-Key header isn't enabled on this server.")
+			return
+		}
+		if !direct[ctx.FullPath()] {
+			badRequest(ctx, "This route is answered from the server's cache, the X-Messari-Api-Key header can only be passed to "+strings.Join(routes, ", ")+".")
+			return
+		}
+		ctx.Set(passthroughKey, true)
+		reqCtx := messari.WithCallerKey(ctx.Request.Context(), apiKey)
+		entry := logging.From(reqCtx).WithField("messari_key", "caller")
+		ctx.Request = ctx.Request.WithContext(logging.WithLogger(reqCtx, entry))
+		ctx.Next()
+	}
+}
+
+// passthrough func returns whether the request's calls to Messari are made with its caller's own API key
+func passthrough(ctx *gin.Context) bool {
+	return ctx.GetBool(passthroughKey)
+}
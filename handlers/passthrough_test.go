@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/config"
+)
+
+func TestPassthroughOnlyOnDirectRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enabled := true
+	server := gin.New()
+	server.Use(withConfig(func() *config.Config {
+		return testConfig(func(c *config.Config) { c.Messari.Passthrough.Enabled = enabled })
+	}), PassthroughMiddleware("/api/asset/:symbolOrSlug"))
+	handler := func(ctx *gin.Context) {
+		if ctx.GetHeader(messariKeyHeader) != "" {
+			t.Error("the caller's key is still in the request's headers")
+		}
+		ctx.String(http.StatusOK, "%v", passthrough(ctx))
+	}
+	server.GET("/api/asset/:symbolOrSlug", handler)
+	server.GET("/api/search", handler)
+
+	tests := []struct {
+		path    string
+		apiKey  string
+		enabled bool
+		status  int
+		body    string
+	}{
+		{path: "/api/asset/btc", apiKey: "caller-key", enabled: true, status: http.StatusOK, body: "true"},
+		{path: "/api/asset/btc", enabled: true, status: http.StatusOK, body: "false"},
+		{path: "/api/asset/btc", apiKey: "caller-key", enabled: false, status: http.StatusBadRequest},
+		// the search is answered from the server's cache
+		{path: "/api/search", apiKey: "caller-key", enabled: true, status: http.StatusBadRequest},
+		{path: "/api/search", enabled: true, status: http.StatusOK, body: "false"},
+	}
+	for _, tt := range tests {
+		enabled = tt.enabled
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.apiKey != "" {
+			req.Header.Set(messariKeyHeader, tt.apiKey)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != tt.status || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s with key %q: got status %d and %q, want %d and %q", tt.path, tt.apiKey, rec.Code, rec.Body.String(), tt.status, tt.body)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/universe"
+)
+
+const (
+	defaultProfileSearchLimit = 20
+	maxProfileSearchLimit     = 100
+)
+
+// profileSearchResp struct is the response json of SearchProfilesHandler
+type profileSearchResp struct {
+	Total   int                   `json:"total"`
+	Results []universe.ProfileHit `json:"results"`
+}
+
+// SearchProfilesHandler func returns a handler for full-text searching the profiles of assets,
+// optionally filtered by sector, category and a comma seperated list of tags
+func SearchProfilesHandler(index *universe.ProfileIndex) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		q := ctx.Query("q")
+		if q == "" {
+			badRequest(ctx, "No search query provided in q.")
+			return
+		}
+		limit := defaultProfileSearchLimit
+		if l := ctx.Query("limit"); l != "" {
+			v, err := strconv.Atoi(l)
+			if err != nil || v < 1 || v > maxProfileSearchLimit {
+				badRequest(ctx, "Invalid limit specified in query.")
+				return
+			}
+			limit = v
+		}
+		filter := universe.ProfileFilter{
+			Sector:   ctx.Query("sector"),
+			Category: ctx.Query("category"),
+		}
+		for _, tag := range strings.Split(ctx.Query("tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+		if index.UpdatedAt().IsZero() {
+			notReady(ctx, "Profile index is still loading, try again shortly.")
+			return
+		}
+
+		hits, total := index.Search(q, filter, limit)
+		ctx.JSON(200, &profileSearchResp{Total: total, Results: hits})
+	}
+}
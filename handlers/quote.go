@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/quote"
+)
+
+// quoteHeader is the response header naming the currency prices, market caps and volumes are quoted in
+const quoteHeader = "X-Quote-Currency"
+
+// queryQuote func returns the "quote" query param, defaulting to USD, and sets quoteHeader to it. Responds
+// with 400 and returns false if fx has no exchange rate for it.
+func queryQuote(ctx *gin.Context, fx *quote.Table) (string, bool) {
+	currency := strings.ToUpper(ctx.DefaultQuery("quote", quote.USD))
+	if !fx.Supports(currency) {
+		badRequest(ctx, fmt.Sprintf("Invalid quote specified in query, %s is not a supported currency.", currency))
+		return "", false
+	}
+	ctx.Header(quoteHeader, currency)
+	return currency, true
+}
+
+// quoteAssets func returns copies of assets converted to currency, using the prices of BTC and ETH in
+// the assets themselves (ie. the snapshot). Responds with 503 and returns false if they have none.
+func quoteAssets(ctx *gin.Context, fx *quote.Table, currency string, assets []messari.Asset, all []messari.Asset) ([]messari.Asset, bool) {
+	if currency == quote.USD {
+		return assets, true
+	}
+	c, err := fx.Converter(currency, quote.ReferencePrices(all))
+	if err != nil {
+		notReady(ctx, fmt.Sprintf("Could not quote in %s: %v.", currency, err))
+		return nil, false
+	}
+	quoted := make([]messari.Asset, len(assets))
+	for i, asset := range assets {
+		quoted[i] = c.Asset(asset)
+	}
+	return quoted, true
+}
+
+// quoteFields func returns fields, as returned by queryFields, with the market data quoteMetrics needs to
+// quote in currency added. The second return value is whether any were added, in which case the response
+// has to be projected back to fields. Nil fields (ie. every field) are returned as is.
+func quoteFields(fields []string, currency string) ([]string, bool) {
+	if currency == quote.USD || fields == nil {
+		return fields, false
+	}
+	prices := messari.MustFields(messari.FieldMarketDataPriceUsd, messari.FieldMarketDataPriceBtc, messari.FieldMarketDataPriceEth)
+	return []string{strings.Join(append(fields, prices...), ",")}, true
+}
+
+// quoteMetrics func returns a copy of m converted to currency, using the prices of BTC and ETH implied by
+// the asset's own market data. Responds with 503 and returns false if it has none.
+func quoteMetrics(ctx *gin.Context, fx *quote.Table, currency string, m messari.Metrics) (messari.Metrics, bool) {
+	if currency == quote.USD {
+		return m, true
+	}
+	c, err := fx.Converter(currency, quote.PricesOf(m.MarketData))
+	if err != nil {
+		notReady(ctx, fmt.Sprintf("Could not quote in %s: %v.", currency, err))
+		return m, false
+	}
+	return c.Metrics(m), true
+}
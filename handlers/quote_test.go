@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/universe"
+)
+
+func TestQuotedMetricsOnlyHaveRequestedFields(t *testing.T) {
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("fields")
+		w.Write([]byte(`{"data": {
+			"market_data": {"price_usd": 100, "price_btc": 0.002, "price_eth": 0.05},
+			"marketcap": {"current_marketcap_usd": 1000000}
+		}}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	m := messari.New("test-api-key", messari.WithBaseURL(u), messari.WithRateLimit(6000, 100))
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.GET("/api/asset/:symbolOrSlug", GetAssetMetricsHandler(m, universe.NewDirectory(), nil))
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/asset/aaa?quote=btc&fields=marketcap/current_marketcap_usd", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if want := "marketcap/current_marketcap_usd,market_data/price_usd,market_data/price_btc,market_data/price_eth"; requested != want {
+		t.Errorf("requested fields %q, want %q", requested, want)
+	}
+
+	var data map[string]map[string]float64
+	if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || len(data["marketcap"]) != 1 {
+		t.Errorf("got %v, want only marketcap/current_marketcap_usd", data)
+	}
+	// BTC is 100 / 0.002 = 50000 USD
+	if got := data["marketcap"]["current_marketcap_usd"]; got != 20 {
+		t.Errorf("got market cap %v BTC, want 20", got)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/auth"
+	"github.com/ultd/messari-server/metrics"
+	"github.com/ultd/messari-server/ratelimit"
+)
+
+// RateLimitMiddleware func returns a middleware responding with 429 and Retry-After when the client
+// has used up its budget in l, a request taking the cost its route has in the request's config (see
+// ConfigMiddleware). Requests to routes costing more than 1 are also refused while l's cap of expensive
+// requests is reached. Clients are told apart by the API key AuthMiddleware authenticated them with,
+// or by their IP without one.
+func RateLimitMiddleware(l *ratelimit.Limiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		c := configOf(ctx).RateLimit.CostOf(route)
+		// the cap is checked first so requests it sheds don't use up the client's budget
+		if c > 1 {
+			release, ok := l.AcquireExpensive()
+			if !ok {
+				metrics.Shed(route, "concurrency")
+				rateLimited(ctx, time.Second, "The server is busy with other expensive requests, try again shortly.")
+				return
+			}
+			defer release()
+		}
+		if ok, retryAfter := l.Allow(clientOf(ctx), c, time.Now()); !ok {
+			metrics.Shed(route, "rate_limit")
+			rateLimited(ctx, retryAfter, "Too many requests, slow down.")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// clientOf func returns what identifies the client making the request to the inbound limits. Clients
+// without an API key are told apart by their IP, see clientIP.
+func clientOf(ctx *gin.Context) string {
+	if v, ok := ctx.Get(apiKeyKey); ok {
+		return "key:" + v.(*auth.Key).ID
+	}
+	return "ip:" + clientIP(ctx)
+}
+
+// clientIP func returns the IP of the client making the request. It's the address of the connection
+// unless that's one of the request config's trusted proxies, in which case the X-Forwarded-For header
+// is walked from the right, skipping the trusted proxies, since any client can put whatever it wants
+// at its left to get a fresh budget. gin's ClientIP trusts the header no matter who sent it.
+func clientIP(ctx *gin.Context) string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(ctx.Request.RemoteAddr))
+	if err != nil {
+		ip = ctx.Request.RemoteAddr
+	}
+	rateLimit := configOf(ctx).RateLimit
+	if len(rateLimit.TrustedProxies) == 0 {
+		return ip
+	}
+	hops := strings.Split(strings.Join(ctx.Request.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		parsed := net.ParseIP(ip)
+		if parsed == nil || !rateLimit.TrustsProxy(parsed) {
+			break
+		}
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			break
+		}
+		ip = hop
+	}
+	return ip
+}
+
+// rateLimited func responds with 429, telling the client to retry after retryAfter
+func rateLimited(ctx *gin.Context, retryAfter time.Duration, message string) {
+	setRetryAfter(ctx, retryAfter)
+	respondError(ctx, http.StatusTooManyRequests, apiError{Code: codeRateLimited, Message: message, Retryable: true})
+}
+
+// setRetryAfter func sets the Retry-After header to d, rounded up to a whole number of seconds
+func setRetryAfter(ctx *gin.Context, d time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
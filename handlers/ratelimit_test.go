@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/config"
+	"github.com/ultd/messari-server/ratelimit"
+)
+
+func TestRateLimitIgnoresForwardedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	cfg := testConfig(nil)
+	server.Use(withConfig(func() *config.Config { return cfg }), RateLimitMiddleware(ratelimit.New(60, 1, 1)))
+	server.GET("/api/search", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	// a client can't get a fresh budget by claiming another IP or connecting from another port
+	for i, forwarded := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		want := http.StatusTooManyRequests
+		if i == 0 {
+			want = http.StatusOK
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.1:%d", 1000+i)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.Header.Set("X-Real-Ip", forwarded)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("request #%d: got status %d, want %d", i+1, rec.Code, want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+	req.RemoteAddr = "192.0.2.2:1000"
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d for another client, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestClientIPBehindTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(func(c *config.Config) {
+		c.RateLimit.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1"}
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "198.51.100.1:1000", forwarded: []string{"203.0.113.1"}, want: "198.51.100.1"},
+		{name: "one proxy", remoteAddr: "192.0.2.1:1000", forwarded: []string{"203.0.113.1"}, want: "203.0.113.1"},
+		{name: "spoofed", remoteAddr: "192.0.2.1:1000", forwarded: []string{"198.51.100.9, 203.0.113.1"}, want: "203.0.113.1"},
+		{name: "proxy chain", remoteAddr: "10.1.2.3:1000", forwarded: []string{"198.51.100.9, 203.0.113.1", "192.0.2.1"}, want: "203.0.113.1"},
+		{name: "no header", remoteAddr: "10.1.2.3:1000", want: "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = req
+			ctx.Set(configKey, cfg)
+			if got := clientIP(ctx); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShedExpensiveRequestsKeepTheirBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	cfg := testConfig(func(c *config.Config) {
+		c.RateLimit.Costs = map[string]int{"/api/aggregate": 10}
+	})
+	l := ratelimit.New(60, 10, 1)
+	server.Use(withConfig(func() *config.Config { return cfg }), RateLimitMiddleware(l))
+	server.GET("/api/aggregate", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/aggregate", nil)
+		req.RemoteAddr = "192.0.2.1:1000"
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	release, ok := l.AcquireExpensive()
+	if !ok {
+		t.Fatal("could not take the only expensive slot")
+	}
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d while the cap is reached, want %d", code, http.StatusTooManyRequests)
+	}
+	release()
+	// the whole burst is still there for the request once a slot is free
+	if code := request(); code != http.StatusOK {
+		t.Errorf("got status %d once a slot is free, want %d", code, http.StatusOK)
+	}
+}
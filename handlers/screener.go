@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/filter"
+	"github.com/ultd/messari-server/logging"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/stats"
+	"github.com/ultd/messari-server/universe"
+)
+
+const (
+	defaultScreenerSort  = "marketcap"
+	defaultScreenerLimit = 50
+	maxScreenerLimit     = 500
+)
+
+// defaultScreenerFields are the fields of each asset returned by ScreenerHandler when none are specified
+var defaultScreenerFields = messari.MustFields(messari.FieldID, messari.FieldSymbol, messari.FieldName, messari.FieldSlug)
+
+// screenerResp struct is the response json of ScreenerHandler
+type screenerResp struct {
+	Sort      string `json:"sort"`
+	Direction string `json:"direction"`
+	// Total is the number of assets matching the filter which have a value to sort by
+	Total      int           `json:"total"`
+	Rows       []screenerRow `json:"rows"`
+	SnapshotAt time.Time     `json:"snapshotAt"`
+}
+
+// screenerRow struct is a single ranked asset in screenerResp
+type screenerRow struct {
+	Rank       int                    `json:"rank"`
+	Value      float64                `json:"value"`
+	Percentile float64                `json:"percentile"`
+	Asset      map[string]interface{} `json:"asset"`
+}
+
+// ScreenerHandler func returns a handler which ranks the assets matching a filter expression by any of
+// their numeric metrics, along with each asset's percentile rank among the matching assets
+func ScreenerHandler(snapshot *universe.Snapshot) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		f, ok := queryFilter(ctx, "filter")
+		if !ok {
+			return
+		}
+		sortField, err := filter.ResolveField(ctx.DefaultQuery("sort", defaultScreenerSort))
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf("Invalid sort specified in query: %v.", err))
+			return
+		}
+		if !sortField.Numeric() {
+			badRequest(ctx, fmt.Sprintf("Invalid sort specified in query: %s is not a number.", sortField.Path))
+			return
+		}
+		direction := ctx.DefaultQuery("dir", "desc")
+		if direction != "asc" && direction != "desc" {
+			badRequest(ctx, "Invalid dir specified in query, expected asc or desc.")
+			return
+		}
+		limit := defaultScreenerLimit
+		if l := ctx.Query("limit"); l != "" {
+			v, err := strconv.Atoi(l)
+			if err != nil || v < 1 || v > maxScreenerLimit {
+				badRequest(ctx, "Invalid limit specified in query.")
+				return
+			}
+			limit = v
+		}
+		fields, ok := queryFields(ctx, messari.AssetSchema)
+		if !ok {
+			return
+		}
+		if fields == nil {
+			fields = defaultScreenerFields
+		}
+
+		assets, snapshotAt := snapshot.Assets()
+		if snapshotAt.IsZero() {
+			notReady(ctx, "Asset snapshot is still loading, try again shortly.")
+			return
+		}
+
+		var matched []messari.Asset
+		var values []float64
+		for _, asset := range assets {
+			if !f.Match(asset) {
+				continue
+			}
+			// assets without a value can't be ranked
+			if v, ok := sortField.Number(asset); ok {
+				matched = append(matched, asset)
+				values = append(values, v)
+			}
+		}
+		percentiles := stats.PercentileRanks(values)
+
+		order := make([]int, len(matched))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			if direction == "asc" {
+				return values[order[i]] < values[order[j]]
+			}
+			return values[order[i]] > values[order[j]]
+		})
+		if len(order) > limit {
+			order = order[:limit]
+		}
+
+		rows := make([]screenerRow, len(order))
+		for rank, i := range order {
+			projected, err := messari.Project(matched[i], fields)
+			if err != nil {
+				logging.From(ctx.Request.Context()).Error(err)
+				internalError(ctx, "An error occured selecting fields of assets.")
+				return
+			}
+			rows[rank] = screenerRow{
+				Rank:       rank + 1,
+				Value:      values[i],
+				Percentile: normalizeFloat(percentiles[i]),
+				Asset:      projected,
+			}
+		}
+
+		ctx.JSON(200, &screenerResp{
+			Sort:       sortField.Path,
+			Direction:  direction,
+			Total:      len(matched),
+			Rows:       rows,
+			SnapshotAt: snapshotAt,
+		})
+	}
+}
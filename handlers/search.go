@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/universe"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+// SearchAssetsHandler func returns a handler for searching assets by name, symbol, slug or
+// tagline, suitable for autocompleting a ticker as it's typed. Results are ranked by how well they
+// matched (their score) and then by market cap, see Directory's Search.
+func SearchAssetsHandler(dir *universe.Directory) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		q := ctx.Query("q")
+		if q == "" {
+			badRequest(ctx, "No search query provided in q.")
+			return
+		}
+		limit := defaultSearchLimit
+		if l := ctx.Query("limit"); l != "" {
+			v, err := strconv.Atoi(l)
+			if err != nil || v < 1 || v > maxSearchLimit {
+				badRequest(ctx, "Invalid limit specified in query.")
+				return
+			}
+			limit = v
+		}
+		if dir.UpdatedAt().IsZero() {
+			notReady(ctx, "Asset directory is still loading, try again shortly.")
+			return
+		}
+		ctx.JSON(200, dir.Search(q, limit))
+	}
+}
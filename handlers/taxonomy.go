@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/taxonomy"
+	"github.com/ultd/messari-server/universe"
+)
+
+// taxonomyResp struct is the response json of GetTaxonomyHandler
+type taxonomyResp struct {
+	Sectors    []taxonomyTerm `json:"sectors"`
+	Categories []taxonomyTerm `json:"categories"`
+	Tags       []taxonomyTerm `json:"tags"`
+	SnapshotAt time.Time      `json:"snapshotAt"`
+}
+
+// taxonomyTerm struct is a single sector, category or tag in taxonomyResp
+type taxonomyTerm struct {
+	Name       string  `json:"name"`
+	AssetCount int     `json:"assetCount"`
+	MarketCap  float64 `json:"marketcap"`
+}
+
+// GetTaxonomyHandler func returns a handler for getting every sector, category and tag used by assets,
+// with how many assets use each and their total market cap
+func GetTaxonomyHandler(snapshot *universe.Snapshot) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		assets, snapshotAt := snapshot.Assets()
+		if snapshotAt.IsZero() {
+			notReady(ctx, "Asset snapshot is still loading, try again shortly.")
+			return
+		}
+
+		sectors := newTermCounter()
+		categories := newTermCounter()
+		tags := newTermCounter()
+		for _, asset := range assets {
+			terms := taxonomy.Of(asset)
+			marketCap := asset.Metrics.Marketcap.CurrentMarketcapUsd
+			sectors.add(terms.Sectors, marketCap)
+			categories.add(terms.Categories, marketCap)
+			tags.add(terms.Tags, marketCap)
+		}
+
+		ctx.JSON(200, &taxonomyResp{
+			Sectors:    sectors.terms(),
+			Categories: categories.terms(),
+			Tags:       tags.terms(),
+			SnapshotAt: snapshotAt,
+		})
+	}
+}
+
+type termCounter map[string]*taxonomyTerm
+
+func newTermCounter() termCounter {
+	return termCounter{}
+}
+
+func (c termCounter) add(terms []string, marketCap float64) {
+	for _, term := range terms {
+		key := taxonomy.Key(term)
+		t, ok := c[key]
+		if !ok {
+			t = &taxonomyTerm{Name: term}
+			c[key] = t
+		}
+		t.AssetCount++
+		t.MarketCap += marketCap
+	}
+}
+
+// terms func returns the counted terms, largest total market cap first
+func (c termCounter) terms() []taxonomyTerm {
+	terms := make([]taxonomyTerm, 0, len(c))
+	for _, t := range c {
+		t.MarketCap = normalizeFloat(t.MarketCap)
+		terms = append(terms, *t)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].MarketCap != terms[j].MarketCap {
+			return terms[i].MarketCap > terms[j].MarketCap
+		}
+		return terms[i].Name < terms[j].Name
+	})
+	return terms
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/universe"
+)
+
+// taxonomyAssets are the assets of testSnapshot. Their terms are spelled differently in their profiles
+// and metrics so only normalized terms match across them.
+const taxonomyAssets = `{"data": [
+	{"id": "1", "symbol": "AAA", "metrics": {"marketcap": {"current_marketcap_usd": 1000000000}},
+		"profile": {"general": {"overview": {"sector": "Lending  Protocols", "tags": "DeFi,  Lending"}}}},
+	{"id": "2", "symbol": "BBB", "metrics": {"marketcap": {"current_marketcap_usd": 500000000},
+		"misc_data": {"sectors": ["lending protocols"], "tags": ["lending"]}}},
+	{"id": "3", "symbol": "CCC", "metrics": {"marketcap": {"current_marketcap_usd": 100000000}},
+		"profile": {"general": {"overview": {"sector": "Payments", "tags": "Payments"}}}}
+]}`
+
+// testSnapshot func returns a Snapshot filled with taxonomyAssets
+func testSnapshot(t *testing.T) *universe.Snapshot {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		w.Write([]byte(taxonomyAssets))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	snapshot := universe.NewSnapshot(messari.New("test-api-key", messari.WithBaseURL(u), messari.WithRateLimit(6000, 100)))
+	if err := snapshot.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+func TestTaxonomyTermsFilterAggregates(t *testing.T) {
+	snapshot := testSnapshot(t)
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.GET("/api/taxonomy", GetTaxonomyHandler(snapshot))
+	server.GET("/api/aggregate", GetAssetMetricsAggregateHandler(snapshot, nil))
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/taxonomy", nil))
+	var taxonomy taxonomyResp
+	if err := json.Unmarshal(rec.Body.Bytes(), &taxonomy); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		param string
+		terms []taxonomyTerm
+	}{
+		{param: "tags", terms: taxonomy.Tags},
+		{param: "sector", terms: taxonomy.Sectors},
+	}
+	for _, tt := range tests {
+		if len(tt.terms) == 0 {
+			t.Fatalf("got no %s from /api/taxonomy", tt.param)
+		}
+		for _, term := range tt.terms {
+			rec := httptest.NewRecorder()
+			target := "/api/aggregate?" + url.Values{tt.param: {term.Name}}.Encode()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: got status %d, want %d", target, rec.Code, http.StatusOK)
+			}
+			var agg assetAggregateMetrics
+			if err := json.Unmarshal(rec.Body.Bytes(), &agg); err != nil {
+				t.Fatal(err)
+			}
+			if agg.MarketCap != term.MarketCap {
+				t.Errorf("%s: got market cap %v, want %v as in /api/taxonomy", target, agg.MarketCap, term.MarketCap)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/ultd/messari-server/handlers"
+
+// TracingMiddleware func returns a middleware starting a span for every request, continuing the trace
+// of the incoming request's headers if there's one. The span is put in the request's context so
+// the spans of the calls made by the handler (ie. to Messari) are its children.
+func TracingMiddleware() gin.HandlerFunc {
+	tracer := otel.Tracer(tracerName)
+	return func(ctx *gin.Context) {
+		parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+		route := ctx.FullPath()
+		name := route
+		if name == "" {
+			name = fmt.Sprintf("HTTP %s", ctx.Request.Method)
+		}
+		spanCtx, span := tracer.Start(parent, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", ctx.Request)...),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("messari-server", route, ctx.Request)...),
+		)
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(ctx.Errors) > 0 {
+			span.RecordError(ctx.Errors.Last())
+		}
+	}
+}
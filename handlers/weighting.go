@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/stats"
+)
+
+const defaultWeighting = "equal"
+
+// weightings maps the "weighting" query param to a func returning how much an asset counts
+// towards weighted statistics
+var weightings = map[string]func(messari.Asset) float64{
+	"equal": func(messari.Asset) float64 {
+		return 1
+	},
+	"marketcap": func(asset messari.Asset) float64 {
+		return asset.Metrics.Marketcap.CurrentMarketcapUsd
+	},
+	"volume": func(asset messari.Asset) float64 {
+		return asset.Metrics.MarketData.VolumeLast24Hours
+	},
+	"liquid_marketcap": func(asset messari.Asset) float64 {
+		if asset.Metrics.Marketcap.LiquidMarketcapUsd == nil {
+			return 0
+		}
+		return *asset.Metrics.Marketcap.LiquidMarketcapUsd
+	},
+}
+
+// changeStats struct holds statistics of the 24 hour change of a group of assets
+type changeStats struct {
+	Weighting      string  `json:"weighting"`
+	WeightedMean   float64 `json:"weightedMean"`
+	WeightedMedian float64 `json:"weightedMedian"`
+	WeightedStdDev float64 `json:"weightedStdDev"`
+	Mean           float64 `json:"mean"`
+	Median         float64 `json:"median"`
+	StdDev         float64 `json:"stdDev"`
+	Min            float64 `json:"min"`
+	Max            float64 `json:"max"`
+}
+
+// twentyFourHourChangeStats func returns statistics of the PercentChangeUsdLast24Hours of assets with
+// weighted ones using the named weighting, which must be a key of weightings
+func twentyFourHourChangeStats(assets []messari.Asset, weighting string) changeStats {
+	weightOf := weightings[weighting]
+	changes := make([]float64, len(assets))
+	weights := make([]float64, len(assets))
+	s := changeStats{Weighting: weighting}
+	for i, asset := range assets {
+		changes[i] = asset.Metrics.MarketData.PercentChangeUsdLast24Hours
+		weights[i] = weightOf(asset)
+		if i == 0 || changes[i] < s.Min {
+			s.Min = changes[i]
+		}
+		if i == 0 || changes[i] > s.Max {
+			s.Max = changes[i]
+		}
+	}
+	s.WeightedMean = normalizeFloat(stats.WeightedMean(changes, weights))
+	s.WeightedMedian = normalizeFloat(stats.WeightedMedian(changes, weights))
+	s.WeightedStdDev = normalizeFloat(stats.WeightedStdDev(changes, weights))
+	s.Mean = normalizeFloat(stats.Mean(changes))
+	s.Median = normalizeFloat(stats.Median(changes))
+	s.StdDev = normalizeFloat(stats.StdDev(changes))
+	s.Min = normalizeFloat(s.Min)
+	s.Max = normalizeFloat(s.Max)
+	return s
+}
+
+// queryWeighting func returns the "weighting" query param, defaulting to equal weighting. Responds
+// with 400 and returns false if it's not one of weightings.
+func queryWeighting(ctx *gin.Context) (string, bool) {
+	weighting := ctx.DefaultQuery("weighting", defaultWeighting)
+	if _, ok := weightings[weighting]; !ok {
+		badRequest(ctx, "Invalid weighting specified in query, expected one of equal, marketcap, volume or liquid_marketcap.")
+		return "", false
+	}
+	return weighting, true
+}
@@ -0,0 +1,90 @@
+// Package logging holds the server's logging setup: logrus writing JSON (or text) lines, a logger per
+// request carrying its request ID, and redaction of secrets such as API keys from every line.
+package logging
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// WithLogger func returns a copy of ctx carrying entry, which From returns
+func WithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// From func returns the logger of ctx (ie. with the request ID of the request ctx belongs to), or
+// the standard logger if ctx doesn't carry one
+func From(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+			return entry
+		}
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// Setup func sets the standard logger's level and its format, "json" or "text". Secrets passed to
+// Redact are removed from every line whatever the format.
+func Setup(level logrus.Level, format string) {
+	logrus.SetLevel(level)
+	var formatter logrus.Formatter = &logrus.JSONFormatter{}
+	if format == "text" {
+		formatter = &logrus.TextFormatter{}
+	}
+	logrus.SetFormatter(&redactingFormatter{Formatter: formatter})
+}
+
+// redacted replaces secrets in logs
+const redacted = "[redacted]"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// Redact func makes sure secret (ie. an API key) never shows up in a log line. Secrets stay redacted
+// even once they're not in use anymore (ie. after a key is rotated).
+func Redact(secret string) {
+	// very short values would redact unrelated text
+	if len(secret) < 8 {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// RedactString func returns s with every secret passed to Redact replaced
+func RedactString(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// redactingFormatter struct is a logrus.Formatter removing secrets from the lines of the Formatter
+// it wraps. Redacting the formatted line rather than the entry covers secrets in messages, fields
+// and errors alike.
+type redactingFormatter struct {
+	logrus.Formatter
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(RedactString(string(b))), nil
+}
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSecretsAreRedacted(t *testing.T) {
+	secret := "0123456789abcdef-test-key"
+	Redact(secret)
+
+	for _, formatter := range []logrus.Formatter{&logrus.JSONFormatter{}, &logrus.TextFormatter{}} {
+		var out bytes.Buffer
+		logger := logrus.New()
+		logger.SetOutput(&out)
+		logger.SetFormatter(&redactingFormatter{Formatter: formatter})
+
+		logger.WithFields(logrus.Fields{
+			"api_key": secret,
+			"url":     "https://data.messari.io/api/v1/assets?key=" + secret,
+		}).WithError(errors.New("rejected key "+secret)).Errorf("request with %s failed", secret)
+
+		line := out.String()
+		if strings.Contains(line, secret) {
+			t.Errorf("%T: secret is in %q", formatter, line)
+		}
+		if got := strings.Count(line, redacted); got != 4 {
+			t.Errorf("%T: got %d redactions in %q, want 4", formatter, got, line)
+		}
+	}
+}
+
+func TestShortSecretsAreNotRedacted(t *testing.T) {
+	Redact("info")
+	if got := RedactString("level=info"); got != "level=info" {
+		t.Errorf("got %q, want a short secret to be ignored", got)
+	}
+}
@@ -1,32 +1,266 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	log "github.com/sirupsen/logrus"
+	"github.com/ultd/messari-server/auth"
+	"github.com/ultd/messari-server/config"
 	"github.com/ultd/messari-server/handlers"
+	"github.com/ultd/messari-server/logging"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/metrics"
+	"github.com/ultd/messari-server/quote"
+	"github.com/ultd/messari-server/ratelimit"
+	"github.com/ultd/messari-server/tracing"
+	"github.com/ultd/messari-server/universe"
 )
 
 func main() {
-	apiKey := os.Getenv("MESSARI_API_KEY")
-
-	if apiKey == "" {
-		panic("need MESSARI_API_KEY in env in order to run server!")
+	cfg, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
-	// setting debug level logging
-	logrus.SetLevel(logrus.DebugLevel)
+	// exchange rates are optional, without them only USD, BTC and ETH can be quoted in
+	var fx *quote.Table
+	if cfg.RatesFile != "" {
+		t, err := quote.Load(cfg.RatesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid rates_file: %v\n", err)
+			os.Exit(2)
+		}
+		fx = t
+	}
 
-	server := gin.Default()
+	store := config.NewStore(cfg, os.Args[1:])
+	applyLogging(cfg)
+	redactKeys(cfg)
 
-	server.GET("/api/asset", handlers.GetAllAssetsHandler(apiKey))
-	server.GET("/api/asset/:symbolOrSlug", handlers.GetAssetMetricsHandler(apiKey))
-	server.GET("/api/aggregate", handlers.GetAssetMetricsAggregateHandler(apiKey))
+	// the API's routes require an API key once a key file is configured
+	var keys *auth.Store
+	if cfg.Auth.KeysFile != "" {
+		keys, err = auth.Load(cfg.Auth.KeysFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid auth.keys_file: %v\n", err)
+			os.Exit(2)
+		}
+	}
 
-	err := server.Run(":8000")
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("could not run server: %v", err)
+		fmt.Fprintf(os.Stderr, "invalid tracing config: %v\n", err)
+		os.Exit(2)
+	}
+
+	// stopped is done once SIGINT or SIGTERM is received
+	stopped, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// background tasks (refreshers, the config watcher) run until workersCtx is canceled, which only
+	// happens once the server has stopped handling requests since they rely on the tasks' data
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	background := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workersCtx)
+		}()
+	}
+
+	// a single client is shared by all handlers so they share its rate limit
+	apiKeys := cfg.Messari.Keys()
+	m := messari.New(apiKeys[0],
+		messari.WithAPIKeys(apiKeys[1:]...),
+		messari.WithBaseURL(cfg.BaseURL()),
+		messari.WithTimeout(cfg.Messari.Timeout),
+		messari.WithRateLimit(cfg.Messari.RequestsPerMinute, cfg.Messari.Burst),
+		messari.WithConcurrency(cfg.Messari.Concurrency),
+		messari.WithPassthroughRateLimit(cfg.Messari.Passthrough.RequestsPerMinute, cfg.Messari.Passthrough.Burst),
+		messari.WithObserver(metrics.Upstream{}),
+	)
+
+	store.OnChange(func(old, new *config.Config) {
+		redactKeys(new)
+		applyLogging(new)
+		m.SetRateLimit(new.Messari.RequestsPerMinute, new.Messari.Burst)
+		m.SetPassthroughRateLimit(new.Messari.Passthrough.RequestsPerMinute, new.Messari.Passthrough.Burst)
+		m.SetAPIKeys(new.Messari.Keys())
+	})
+	background(func(ctx context.Context) {
+		if err := store.Watch(ctx); err != nil {
+			logrus.Errorf("config won't be reloaded: %v", err)
+		}
+	})
+
+	// snapshotTTLChanged is signaled whenever a reload changes the snapshot's TTL, so it applies right away
+	snapshotTTLChanged := make(chan struct{}, 1)
+	store.OnChange(func(old, new *config.Config) {
+		if old.Cache.SnapshotTTL == new.Cache.SnapshotTTL {
+			return
+		}
+		select {
+		case snapshotTTLChanged <- struct{}{}:
+		default:
+		}
+	})
+
+	// the directory and the profile index are built from the snapshot rather than paging through Messari again
+	dir := universe.NewDirectory()
+	profiles := universe.NewProfileIndex()
+	snapshot := universe.NewSnapshot(m)
+	snapshot.OnRefresh(dir.Update)
+	snapshot.OnRefresh(profiles.Update)
+	background(func(ctx context.Context) {
+		snapshot.Run(ctx, func() time.Duration { return store.Config().Cache.SnapshotTTL }, snapshotTTLChanged)
+	})
+
+	metrics.CacheAge("directory", dir.UpdatedAt)
+	metrics.CacheAge("profiles", profiles.UpdatedAt)
+	metrics.CacheAge("snapshot", snapshot.UpdatedAt)
+
+	// gin's own debug output (ie. the list of routes) isn't structured so it's only printed at the debug level
+	if cfg.Log.Level != "debug" && cfg.Log.Level != "trace" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	server := gin.New()
+	server.Use(
+		// the config is loaded once per request so reloads don't change it halfway through one
+		handlers.ConfigMiddleware(store),
+		handlers.RequestIDMiddleware(),
+		handlers.AccessLogMiddleware(),
+		handlers.RecoveryMiddleware(),
+		handlers.MetricsMiddleware(),
+		handlers.TracingMiddleware(),
+	)
+	server.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	server.NoRoute(handlers.NotFoundHandler())
+
+	server.GET("/healthz", handlers.HealthzHandler())
+	server.GET("/readyz", handlers.ReadyzHandler(
+		handlers.Check{Name: "config", Check: func() error {
+			if store.Config() == nil {
+				return fmt.Errorf("config isn't loaded")
+			}
+			return nil
+		}},
+		handlers.WarmedCheck("asset directory", dir.UpdatedAt),
+		handlers.WarmedCheck("profile index", profiles.UpdatedAt),
+		handlers.WarmedCheck("asset snapshot", snapshot.UpdatedAt),
+		handlers.BreakerCheck(m),
+	))
+
+	// clients are limited after being authenticated so they're told apart by API key rather than IP
+	limiter := ratelimit.New(cfg.RateLimit.RequestsPerMinute, cfg.RateLimit.Burst, cfg.RateLimit.MaxExpensive)
+	store.OnChange(func(old, new *config.Config) {
+		limiter.Set(new.RateLimit.RequestsPerMinute, new.RateLimit.Burst, new.RateLimit.MaxExpensive)
+	})
+	rateLimit := handlers.RateLimitMiddleware(limiter)
+
+	api := server.Group("/")
+	if keys != nil {
+		background(func(ctx context.Context) {
+			if err := keys.Watch(ctx); err != nil {
+				logrus.Errorf("API keys won't be reloaded: %v", err)
+			}
+		})
+		// quotas are charged last so requests shed by the rate limits don't count towards them
+		api.Use(handlers.AuthMiddleware(keys), rateLimit, handlers.QuotaMiddleware(keys))
+
+		admin := api.Group("/admin", handlers.RequireAdmin())
+		admin.GET("/usage", handlers.UsageHandler(keys))
+		admin.GET("/usage/:id", handlers.KeyUsageHandler(keys))
+	} else {
+		logrus.Warn("no API keys file is configured, requests aren't authenticated")
+		api.Use(rateLimit)
+	}
+	// only the routes calling Messari for each request can be made with the caller's own key
+	api.Use(handlers.PassthroughMiddleware("/api/asset", "/api/asset/:symbolOrSlug", "/api/assets/batch"))
+
+	api.GET("/status", handlers.StatusHandler(m,
+		handlers.Cache{Name: "directory", UpdatedAt: dir.UpdatedAt},
+		handlers.Cache{Name: "profiles", UpdatedAt: profiles.UpdatedAt},
+		handlers.Cache{Name: "snapshot", UpdatedAt: snapshot.UpdatedAt},
+	))
+
+	api.GET("/api/asset", handlers.GetAllAssetsHandler(m, snapshot, fx))
+	api.GET("/api/asset/:symbolOrSlug", handlers.GetAssetMetricsHandler(m, dir, fx))
+	api.POST("/api/assets/batch", handlers.FeatureToggle(func(f config.Features) bool { return f.Batch }), handlers.GetAssetsMetricsBatchHandler(m, dir))
+	api.GET("/api/search", handlers.SearchAssetsHandler(dir))
+	api.GET("/api/profiles/search", handlers.FeatureToggle(func(f config.Features) bool { return f.ProfileSearch }), handlers.SearchProfilesHandler(profiles))
+	api.GET("/api/aggregate", handlers.GetAssetMetricsAggregateHandler(snapshot, fx))
+	api.GET("/api/aggregate/breakdown", handlers.GetAssetMetricsBreakdownHandler(snapshot, fx))
+	api.GET("/api/screener", handlers.FeatureToggle(func(f config.Features) bool { return f.Screener }), handlers.ScreenerHandler(snapshot))
+	api.GET("/api/movers", handlers.FeatureToggle(func(f config.Features) bool { return f.Movers }), handlers.MoversHandler(snapshot))
+	api.GET("/api/taxonomy", handlers.GetTaxonomyHandler(snapshot))
+
+	srv := &http.Server{
+		Addr:    cfg.Listen,
+		Handler: server,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		logrus.Infof("listening on %s", cfg.Listen)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		logrus.Fatalf("could not run server: %v", err)
+	case <-stopped.Done():
+	}
+	// a second signal kills the server right away
+	stop()
+
+	timeout := store.Config().ShutdownTimeout
+	logrus.Infof("shutting down, waiting up to %s for in-flight requests", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logrus.Warnf("in-flight requests didn't finish in time, closing their connections: %v", err)
+		srv.Close()
+	}
+
+	stopWorkers()
+	workers.Wait()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logrus.Errorf("could not flush traces: %v", err)
+	}
+	logrus.Info("server stopped")
+	flushLogs()
+}
+
+// flushLogs func syncs logrus' output to disk when it's a file, stderr is unbuffered
+func flushLogs() {
+	if f, ok := logrus.StandardLogger().Out.(interface{ Sync() error }); ok {
+		f.Sync()
+	}
+}
+
+// applyLogging func sets up logging as cfg says
+func applyLogging(cfg *config.Config) {
+	level, _ := logrus.ParseLevel(cfg.Log.Level)
+	logging.Setup(level, cfg.Log.Format)
+}
+
+// redactKeys func keeps cfg's Messari API keys out of the logs
+func redactKeys(cfg *config.Config) {
+	for _, key := range cfg.Messari.Keys() {
+		logging.Redact(key)
 	}
 }
@@ -0,0 +1,109 @@
+package messari
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the Client's calls without making a request while Messari's API
+// is failing
+var ErrCircuitOpen = errors.New("messari: circuit breaker is open, Messari's API is failing")
+
+// BreakerState is the state of the Client's circuit breaker
+type BreakerState int
+
+// A const type of BreakerState
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every request right away until its cooldown is over
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial request through, which closes the breaker if it succeeds
+	BreakerHalfOpen
+)
+
+// String func returns the name of a BreakerState
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// breaker struct opens after threshold consecutive failed requests and stays open for cooldown
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// trial is whether the half open breaker's trial request is in flight
+	trial bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow func returns ErrCircuitOpen if a request can't be made right now
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// record func records the outcome of a request let through by allow
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if !failed {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// cancel func gives up a request let through by allow which was never made (ie. its context was
+// canceled while waiting for the rate limit)
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// State func returns the current BreakerState
+func (b *breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
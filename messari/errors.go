@@ -0,0 +1,64 @@
+package messari
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// ErrInvalidAsset is returned by the Client's calls for an asset without making a request when the
+// symbol or slug can't be one (ie. "..")
+var ErrInvalidAsset = errors.New("messari: invalid asset symbol or slug")
+
+// APIError struct is returned when Messari's API responds with an unexpected status code
+type APIError struct {
+	// StatusCode is the status code of Messari's response
+	StatusCode int
+	Endpoint   string
+	// Message is the error message in Messari's response, if there was one
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("messari returned status %d for %s: %s", e.StatusCode, e.Endpoint, e.Message)
+	}
+	return fmt.Sprintf("messari returned status %d for %s instead of 200", e.StatusCode, e.Endpoint)
+}
+
+// newAPIError func returns the APIError of resp, reading Messari's error message from its body
+func newAPIError(endpoint string, resp *http.Response) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode, Endpoint: endpoint}
+	var body struct {
+		Status struct {
+			ErrorMessage string `json:"error_message"`
+		} `json:"status"`
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err == nil && json.Unmarshal(b, &body) == nil {
+		e.Message = body.Status.ErrorMessage
+	}
+	return e
+}
+
+// IsNotFound func returns whether err is Messari responding that what was requested (ie. an asset)
+// doesn't exist
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsTimeout func returns whether err is a request against Messari's API which took too long, including
+// waiting for the rate limit
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,254 @@
+package messari
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Field is a path to a field in a Messari API response, with each level seperated by a "/"
+// (ie. "metrics/market_data/price_usd") as the API's "fields" query parameter dictates
+type Field string
+
+// Commonly used fields of an Asset returned by GetAllAssets
+const (
+	FieldID                   Field = "id"
+	FieldName                 Field = "name"
+	FieldSymbol               Field = "symbol"
+	FieldSlug                 Field = "slug"
+	FieldMetrics              Field = "metrics"
+	FieldMetricsMarketData    Field = "metrics/market_data"
+	FieldMetricsMarketcap     Field = "metrics/marketcap"
+	FieldMetricsSupply        Field = "metrics/supply"
+	FieldMetricsRiskMetrics   Field = "metrics/risk_metrics"
+	FieldMetricsStakingStats  Field = "metrics/staking_stats"
+	FieldMetricsMiscData      Field = "metrics/misc_data"
+	FieldMetricsPriceUsd      Field = "metrics/market_data/price_usd"
+	FieldMetricsVolume24Hours Field = "metrics/market_data/volume_last_24_hours"
+	FieldMetricsMarketcapUsd  Field = "metrics/marketcap/current_marketcap_usd"
+	FieldProfile              Field = "profile"
+	FieldProfileGeneral       Field = "profile/general"
+	FieldProfileOverview      Field = "profile/general/overview"
+	FieldProfileTagline       Field = "profile/general/overview/tagline"
+	FieldProfileCategory      Field = "profile/general/overview/category"
+	FieldProfileSector        Field = "profile/general/overview/sector"
+	FieldProfileTags          Field = "profile/general/overview/tags"
+	FieldProfileEconomics     Field = "profile/economics"
+	FieldProfileTechnology    Field = "profile/technology"
+	FieldProfileGovernance    Field = "profile/governance"
+)
+
+// Commonly used fields of the data returned by GetAssetMetrics
+const (
+	FieldMarketData          Field = "market_data"
+	FieldMarketcap           Field = "marketcap"
+	FieldSupply              Field = "supply"
+	FieldRiskMetrics         Field = "risk_metrics"
+	FieldMarketDataPriceUsd  Field = "market_data/price_usd"
+	FieldMarketDataPriceBtc  Field = "market_data/price_btc"
+	FieldMarketDataPriceEth  Field = "market_data/price_eth"
+	FieldMarketcapCurrentUsd Field = "marketcap/current_marketcap_usd"
+)
+
+// FieldSchema is the response shape a Field is resolved against. Each Messari endpoint
+// returns a different shape so the same path isn't valid everywhere.
+type FieldSchema int
+
+// A const type of FieldSchema, one per endpoint the Client supports
+const (
+	// AssetSchema is the shape of each Asset returned by GetAllAssets
+	AssetSchema FieldSchema = iota
+	// AssetMetaDataSchema is the shape of the data returned by GetAsset
+	AssetMetaDataSchema
+	// AssetMetricsSchema is the shape of the data returned by GetAssetMetrics
+	AssetMetricsSchema
+)
+
+var schemaTypes = map[FieldSchema]reflect.Type{
+	AssetSchema:         reflect.TypeOf(Asset{}),
+	AssetMetaDataSchema: reflect.TypeOf(AssetMetaData{}),
+	AssetMetricsSchema:  reflect.TypeOf(AssetMetricsMetadata{}),
+}
+
+// String func returns the name of a FieldSchema
+func (s FieldSchema) String() string {
+	switch s {
+	case AssetSchema:
+		return "asset"
+	case AssetMetaDataSchema:
+		return "asset metadata"
+	case AssetMetricsSchema:
+		return "asset metrics"
+	}
+	return fmt.Sprintf("FieldSchema(%d)", int(s))
+}
+
+// ValidFor func returns an error if the field path doesn't exist in the given schema
+func (f Field) ValidFor(schema FieldSchema) error {
+	t, ok := schemaTypes[schema]
+	if !ok {
+		return fmt.Errorf("unknown field schema %s", schema)
+	}
+	if f == "" {
+		return fmt.Errorf("field path is empty")
+	}
+	if !hasPath(t, strings.Split(string(f), "/")) {
+		return fmt.Errorf("field %q does not exist in %s schema", f, schema)
+	}
+	return nil
+}
+
+// Fields func serializes fields into the form expected by the Options' Fields of the Client,
+// a single comma seperated string. Duplicate fields are dropped. Returns an error if a field doesn't
+// exist in any schema, use ParseFields for fields which come from user input.
+func Fields(fields ...Field) ([]string, error) {
+	for _, f := range fields {
+		if !f.known() {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+	}
+	return joinFields(fields), nil
+}
+
+// MustFields func is like Fields but panics if a field doesn't exist in any schema. It's meant for
+// fields which are constants of this package.
+func MustFields(fields ...Field) []string {
+	joined, err := Fields(fields...)
+	if err != nil {
+		panic("messari: " + err.Error())
+	}
+	return joined
+}
+
+// ParseFields func parses a comma seperated list of field paths (ie. "symbol,metrics/market_data")
+// and validates each against the given schema. Returns nil if raw is empty.
+func ParseFields(schema FieldSchema, raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var fields []Field
+	for _, part := range strings.Split(raw, ",") {
+		f := Field(strings.Trim(strings.TrimSpace(part), "/"))
+		if err := f.ValidFor(schema); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return joinFields(fields), nil
+}
+
+// Project func returns v (a response value such as an Asset) as a map holding only the given fields,
+// as returned by Fields or ParseFields. It's used to apply a field selection locally to data which
+// didn't come straight from Messari's API.
+func Project(v interface{}, fields []string) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal value to project: %w", err)
+	}
+	var full map[string]interface{}
+	if err := json.Unmarshal(b, &full); err != nil {
+		return nil, fmt.Errorf("could not unmarshal value to project: %w", err)
+	}
+	if len(fields) == 0 {
+		return full, nil
+	}
+
+	projected := map[string]interface{}{}
+	for _, joined := range fields {
+		for _, field := range strings.Split(joined, ",") {
+			copyPath(projected, full, strings.Split(field, "/"))
+		}
+	}
+	return projected, nil
+}
+
+// copyPath func copies the value at path in src to the same path in dst
+func copyPath(dst, src map[string]interface{}, path []string) {
+	v, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = v
+		return
+	}
+	srcChild, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	dstChild, ok := dst[path[0]].(map[string]interface{})
+	if !ok {
+		dstChild = map[string]interface{}{}
+		dst[path[0]] = dstChild
+	}
+	copyPath(dstChild, srcChild, path[1:])
+}
+
+func (f Field) known() bool {
+	for schema := range schemaTypes {
+		if f.ValidFor(schema) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func joinFields(fields []Field) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	seen := make(map[Field]bool, len(fields))
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		parts = append(parts, string(f))
+	}
+	// have to put all fields in single string comma seperated as API dictates
+	return []string{strings.Join(parts, ",")}
+}
+
+// hasPath walks t following the json tag names in path
+func hasPath(t reflect.Type, path []string) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if len(path) == 0 {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		// keys of maps aren't known ahead of time
+		return path[0] != "" && hasPath(t.Elem(), path[1:])
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.Anonymous {
+				if hasPath(sf.Type, path) {
+					return true
+				}
+				continue
+			}
+			if jsonName(sf) == path[0] {
+				return hasPath(sf.Type, path[1:])
+			}
+		}
+	}
+	return false
+}
+
+func jsonName(sf reflect.StructField) string {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return sf.Name
+}
@@ -0,0 +1,29 @@
+package messari
+
+import "testing"
+
+func TestFields(t *testing.T) {
+	got, err := Fields(FieldSymbol, FieldMetricsPriceUsd, FieldSymbol, FieldMarketDataPriceUsd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "symbol,metrics/market_data/price_usd,market_data/price_usd" {
+		t.Errorf("got %q, want the fields joined in a single string without duplicates", got)
+	}
+
+	if got, err := Fields(); err != nil || got != nil {
+		t.Errorf("got %q and %v for no fields, want nil", got, err)
+	}
+	if _, err := Fields(FieldSymbol, Field("metrics/not_a_field")); err == nil {
+		t.Error("got no error for an unknown field")
+	}
+}
+
+func TestMustFieldsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("didn't panic for an unknown field")
+		}
+	}()
+	MustFields(Field("not_a_field"))
+}
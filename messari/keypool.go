@@ -0,0 +1,368 @@
+package messari
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// How long an API key is taken out of rotation for
+const (
+	// rejectedKeyCooldown is for a key Messari responded 401 to, which was likely revoked
+	rejectedKeyCooldown = 10 * time.Minute
+	// limitedKeyCooldown is for a key Messari responded 429 to without saying when it can be used again
+	limitedKeyCooldown = time.Minute
+	// maxKeyCooldown caps how long Messari's headers can take a key out of rotation for
+	maxKeyCooldown = time.Hour
+)
+
+// ErrNoAPIKey is returned by the Client's calls without making a request while every one of its API
+// keys is out of rotation
+var ErrNoAPIKey = errors.New("messari: every API key is out of rotation")
+
+// WithAPIKeys func returns an Option which adds apiKeys to the API key given to New. Requests are
+// spread across the keys, each having the rate limit set by WithRateLimit, and a key Messari rejects
+// or rate limits is taken out of rotation for a while.
+func WithAPIKeys(apiKeys ...string) Option {
+	return func(m *Client) {
+		m.keys.set(append(m.keys.apiKeys(), apiKeys...))
+	}
+}
+
+// SetAPIKeys func replaces the API keys used by requests made from now on, the keys which were
+// already used keep their state (ie. being out of rotation)
+func (m *Client) SetAPIKeys(apiKeys []string) {
+	m.keys.set(apiKeys)
+}
+
+// poolKey struct is an API key of the keyPool and how requests made with it have been going, its
+// fields other than apiKey, id and limiter are guarded by the keyPool's mu
+type poolKey struct {
+	apiKey string
+	// id is a fingerprint of apiKey, which tells the keys apart in logs and Status without giving them away
+	id      string
+	limiter *rate.Limiter
+
+	requests   int64
+	errors     int64
+	lastStatus int
+	// outUntil is when the key is back in rotation, outReason why it was taken out
+	outUntil  time.Time
+	outReason string
+	// limit, remaining and reset are what Messari's rate limit headers last said, -1 when unknown
+	limit     int64
+	remaining int64
+	reset     time.Time
+}
+
+// keyPool struct holds the Client's own API keys and picks which one each request is made with
+type keyPool struct {
+	mu                sync.Mutex
+	requestsPerMinute float64
+	burst             int
+	keys              []*poolKey
+	// next is where picking a key starts, so requests are spread across keys
+	next int
+}
+
+func newKeyPool(apiKey string) *keyPool {
+	p := &keyPool{
+		// Messari allows 30 requests per minute with an API key
+		requestsPerMinute: 30,
+		burst:             5,
+	}
+	p.set([]string{apiKey})
+	return p
+}
+
+func fingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:4])
+}
+
+// set func replaces the pool's keys by apiKeys, ignoring empty and repeated ones. It does nothing
+// if there's no key left.
+func (p *keyPool) set(apiKeys []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := make(map[string]*poolKey, len(p.keys))
+	for _, k := range p.keys {
+		current[k.apiKey] = k
+	}
+	keys := make([]*poolKey, 0, len(apiKeys))
+	seen := make(map[string]bool, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		if apiKey == "" || seen[apiKey] {
+			continue
+		}
+		seen[apiKey] = true
+		k, ok := current[apiKey]
+		if !ok {
+			k = &poolKey{
+				apiKey:    apiKey,
+				id:        fingerprint(apiKey),
+				limiter:   rate.NewLimiter(rate.Limit(p.requestsPerMinute/60), p.burst),
+				limit:     -1,
+				remaining: -1,
+			}
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	p.keys = keys
+	p.next = 0
+}
+
+func (p *keyPool) apiKeys() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	apiKeys := make([]string, len(p.keys))
+	for i, k := range p.keys {
+		apiKeys[i] = k.apiKey
+	}
+	return apiKeys
+}
+
+func (p *keyPool) setRateLimit(requestsPerMinute float64, burst int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requestsPerMinute, p.burst = requestsPerMinute, burst
+	for _, k := range p.keys {
+		k.limiter.SetLimit(rate.Limit(requestsPerMinute / 60))
+		k.limiter.SetBurst(burst)
+	}
+}
+
+// usable func returns whether k can be picked at now, p.mu must be held
+func (k *poolKey) usable(now time.Time) bool {
+	if now.Before(k.outUntil) {
+		return false
+	}
+	// Messari said the key has no requests left until reset
+	return k.remaining != 0 || !now.Before(k.reset)
+}
+
+// pick func returns the key in rotation whose rate limiter would let a request through the soonest,
+// starting from next so keys which are as good are picked in turn. It returns ErrNoAPIKey if every key
+// is out of rotation.
+func (p *keyPool) pick(now time.Time) (*poolKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var best *poolKey
+	bestAt, bestDelay := 0, time.Duration(math.MaxInt64)
+	for i := range p.keys {
+		at := (p.next + i) % len(p.keys)
+		k := p.keys[at]
+		if !k.usable(now) {
+			continue
+		}
+		if delay := delayOf(k.limiter, now); best == nil || delay < bestDelay {
+			best, bestAt, bestDelay = k, at, delay
+		}
+	}
+	if best == nil {
+		return nil, ErrNoAPIKey
+	}
+	p.next = (bestAt + 1) % len(p.keys)
+	return best, nil
+}
+
+// delayOf func returns how long l would make a request wait at now, without taking a token from it
+func delayOf(l *rate.Limiter, now time.Time) time.Duration {
+	missing := 1 - l.TokensAt(now)
+	if missing <= 0 || l.Limit() == rate.Inf {
+		return 0
+	}
+	if l.Limit() <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(missing / float64(l.Limit()) * float64(time.Second))
+}
+
+func (p *keyPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+// usableCount func returns how many keys are in rotation at now
+func (p *keyPool) usableCount(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, k := range p.keys {
+		if k.usable(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// canRotate func returns whether a request which got resp can be made again right away with another
+// of the Client's API keys, as resp rejected or rate limited the key it was made with. rotations is how
+// many times the request was made again with another key already, it's made with at most as many keys
+// as the Client has.
+func (m *Client) canRotate(ctx context.Context, resp *http.Response, rotations int) bool {
+	if resp == nil || ctx.Err() != nil || callerKeyOf(ctx) != "" {
+		return false
+	}
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	if rotations+1 >= m.keys.size() {
+		return false
+	}
+	// the key resp was made with has been taken out of rotation already
+	return m.keys.usableCount(time.Now()) > 0
+}
+
+// record func records a request made with k at now which got resp (nil if it failed without one),
+// taking k out of rotation if Messari rejected or rate limited it
+func (p *keyPool) record(k *poolKey, resp *http.Response, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k.requests++
+	if resp == nil {
+		k.errors++
+		k.lastStatus = 0
+		return
+	}
+	k.lastStatus = resp.StatusCode
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusTooManyRequests {
+		k.errors++
+	}
+	if limit, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Limit"), 10, 64); err == nil {
+		k.limit = limit
+	}
+	if remaining, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+		k.remaining = remaining
+	}
+	if reset, ok := resetOf(resp.Header.Get("X-RateLimit-Reset"), now); ok {
+		k.reset = reset
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		p.takeOut(k, now.Add(rejectedKeyCooldown), "rejected")
+	case http.StatusTooManyRequests:
+		until := now.Add(limitedKeyCooldown)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			until = now.Add(time.Duration(seconds) * time.Second)
+		} else if k.reset.After(now) {
+			until = k.reset
+		}
+		if until.Sub(now) > maxKeyCooldown {
+			until = now.Add(maxKeyCooldown)
+		}
+		p.takeOut(k, until, "rate limited")
+	}
+}
+
+// takeOut func takes k out of rotation until until for reason, p.mu must be held
+func (p *keyPool) takeOut(k *poolKey, until time.Time, reason string) {
+	k.outUntil, k.outReason = until, reason
+	logrus.WithField("messari_key", k.id).Warnf("Messari API key %s was %s, it's out of rotation until %s", k.id, reason, until.Format(time.RFC3339))
+}
+
+// resetOf func parses a rate limit reset header, which is either a unix time or a number of seconds
+// from now
+func resetOf(v string, now time.Time) (time.Time, bool) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	// no rate limit window lasts for anywhere near as long as the unix time of 2001
+	if n > 1e9 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
+
+// KeyStatus struct reports how the requests made with one of the Client's API keys have been going
+type KeyStatus struct {
+	// ID is a fingerprint of the key (the start of its SHA-256 hash)
+	ID string `json:"id"`
+	// State is "ok", or "out" while the key is out of rotation for Reason until Until
+	State  string     `json:"state"`
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+	// Requests counts the requests made with the key, Errors those which failed or got a 5xx, 401 or
+	// 429 response
+	Requests   int64 `json:"requests"`
+	Errors     int64 `json:"errors"`
+	LastStatus int   `json:"lastStatus"`
+	// RateLimit is what Messari's rate limit headers last said about the key, nil until they did
+	RateLimit *KeyRateLimit `json:"rateLimit"`
+	// Available is how many requests the server's own rate limit lets through right away with the key
+	Available float64 `json:"available"`
+}
+
+// KeyRateLimit struct is the rate limit Messari reported for an API key
+type KeyRateLimit struct {
+	Limit     int64      `json:"limit"`
+	Remaining int64      `json:"remaining"`
+	Reset     *time.Time `json:"reset,omitempty"`
+}
+
+func (p *keyPool) status(now time.Time) []KeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := make([]KeyStatus, len(p.keys))
+	for i, k := range p.keys {
+		s := KeyStatus{
+			ID:         k.id,
+			State:      "ok",
+			Requests:   k.requests,
+			Errors:     k.errors,
+			LastStatus: k.lastStatus,
+			Available:  available(k.limiter, now),
+		}
+		if !k.usable(now) {
+			s.State = "out"
+			if now.Before(k.outUntil) {
+				until := k.outUntil
+				s.Reason, s.Until = k.outReason, &until
+			} else {
+				until := k.reset
+				s.Reason, s.Until = "no requests left", &until
+			}
+		}
+		if k.limit >= 0 || k.remaining >= 0 {
+			s.RateLimit = &KeyRateLimit{Limit: k.limit, Remaining: k.remaining}
+			if !k.reset.IsZero() {
+				reset := k.reset
+				s.RateLimit.Reset = &reset
+			}
+		}
+		statuses[i] = s
+	}
+	return statuses
+}
+
+// budget func returns the rate limit of the keys in rotation at now and how many requests they can
+// make right away
+func (p *keyPool) budget(now time.Time) RateBudget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var budget RateBudget
+	for _, k := range p.keys {
+		if !k.usable(now) {
+			continue
+		}
+		budget.RequestsPerMinute += float64(k.limiter.Limit()) * 60
+		budget.Burst += k.limiter.Burst()
+		budget.Available += available(k.limiter, now)
+	}
+	return budget
+}
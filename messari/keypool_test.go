@@ -0,0 +1,222 @@
+package messari
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keyServer struct is a test server's handler responding to each API key with its status
+type keyServer struct {
+	mu       sync.Mutex
+	statuses map[string]int
+	headers  map[string]http.Header
+	requests map[string]int
+}
+
+func (s *keyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	apiKey := r.Header.Get("x-messari-api-key")
+	s.requests[apiKey]++
+	for name, values := range s.headers[apiKey] {
+		w.Header()[name] = values
+	}
+	status, ok := s.statuses[apiKey]
+	if !ok {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write([]byte("{}"))
+}
+
+func (s *keyServer) requestsOf(apiKey string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[apiKey]
+}
+
+// rotationCounter struct is an Observer counting rotations by status
+type rotationCounter struct {
+	mu        sync.Mutex
+	rotations map[int]int
+}
+
+func (c *rotationCounter) ObserveRequest(string, int, time.Duration) {}
+
+func (c *rotationCounter) ObserveRotation(endpoint string, status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rotations[status]++
+}
+
+func TestKeysAreTakenOutOfRotation(t *testing.T) {
+	s := &keyServer{
+		statuses: map[string]int{"test-api-key": http.StatusUnauthorized, "limited-key": http.StatusTooManyRequests},
+		headers:  map[string]http.Header{"limited-key": {"Retry-After": {"120"}}},
+		requests: map[string]int{},
+	}
+	observer := &rotationCounter{rotations: map[int]int{}}
+	m := newTestClient(t, s.ServeHTTP, WithAPIKeys("limited-key", "good-key"), WithObserver(observer))
+
+	// the rejected and rate limited keys are rotated away from, although requests aren't retried
+	if _, err := m.GetAsset(context.Background(), "btc", nil); err != nil {
+		t.Fatalf("got error %v, want the request made with the good key", err)
+	}
+	for _, apiKey := range []string{"test-api-key", "limited-key", "good-key"} {
+		if got := s.requestsOf(apiKey); got != 1 {
+			t.Errorf("got %d requests with %s, want 1", got, apiKey)
+		}
+	}
+
+	if observer.rotations[http.StatusUnauthorized] != 1 || observer.rotations[http.StatusTooManyRequests] != 1 {
+		t.Errorf("got rotations %v, want one after the 401 and one after the 429", observer.rotations)
+	}
+
+	now := time.Now()
+	want := map[string]struct {
+		reason string
+		until  time.Duration
+	}{
+		fingerprint("test-api-key"): {"rejected", rejectedKeyCooldown},
+		fingerprint("limited-key"):  {"rate limited", 120 * time.Second},
+		fingerprint("good-key"):     {"", 0},
+	}
+	for _, k := range m.Status().Keys {
+		w := want[k.ID]
+		if w.reason == "" {
+			if k.State != "ok" {
+				t.Errorf("key %s is %s, want ok", k.ID, k.State)
+			}
+			continue
+		}
+		if k.State != "out" || k.Reason != w.reason || k.Until == nil || k.Until.Sub(now) > w.until || k.Until.Sub(now) < w.until-time.Minute {
+			t.Errorf("got key %s %s (%s) until %v, want out (%s) for %s", k.ID, k.State, k.Reason, k.Until, w.reason, w.until)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.GetAsset(context.Background(), "btc", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a, b, c := s.requestsOf("test-api-key"), s.requestsOf("limited-key"), s.requestsOf("good-key"); a != 1 || b != 1 || c != 4 {
+		t.Errorf("got %d, %d and %d requests with each key, want only the good key used", a, b, c)
+	}
+}
+
+func TestNoAPIKeyLeft(t *testing.T) {
+	s := &keyServer{
+		statuses: map[string]int{"test-api-key": http.StatusTooManyRequests, "other-key": http.StatusTooManyRequests},
+		headers: map[string]http.Header{
+			"test-api-key": {"X-RateLimit-Reset": {"60"}},
+			"other-key":    {"X-RateLimit-Remaining": {"0"}},
+		},
+		requests: map[string]int{},
+	}
+	m := newTestClient(t, s.ServeHTTP, WithAPIKeys("other-key"))
+
+	var apiErr *APIError
+	if _, err := m.GetAsset(context.Background(), "btc", nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got error %v, want Messari's 429", err)
+	}
+	if _, err := m.GetAsset(context.Background(), "btc", nil); !errors.Is(err, ErrNoAPIKey) {
+		t.Fatalf("got error %v, want ErrNoAPIKey", err)
+	}
+	if a, b := s.requestsOf("test-api-key"), s.requestsOf("other-key"); a != 1 || b != 1 {
+		t.Errorf("got %d and %d requests with each key, want none once they're out of rotation", a, b)
+	}
+	if budget := m.Status().RateLimit; budget.RequestsPerMinute != 0 || budget.Available != 0 {
+		t.Errorf("got rate budget %+v, want none", budget)
+	}
+}
+
+func TestKeyPoolRecord(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		// outUntil is how long the key is taken out of rotation for, reset when Messari said it was
+		outUntil time.Duration
+		reset    time.Duration
+		// usable is whether the key can still be picked right away
+		usable bool
+	}{
+		{name: "ok", status: http.StatusOK, usable: true},
+		{name: "server error", status: http.StatusBadGateway, usable: true},
+		{name: "rejected", status: http.StatusUnauthorized, outUntil: rejectedKeyCooldown},
+		{name: "rate limited", status: http.StatusTooManyRequests, outUntil: limitedKeyCooldown},
+		{name: "retry after", status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "30"}, outUntil: 30 * time.Second},
+		{name: "retry after over reset", status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "30", "X-RateLimit-Reset": "300"}, outUntil: 30 * time.Second, reset: 300 * time.Second},
+		{name: "reset in seconds", status: http.StatusTooManyRequests, headers: map[string]string{"X-RateLimit-Reset": "300"}, outUntil: 300 * time.Second, reset: 300 * time.Second},
+		{name: "reset in unix time", status: http.StatusTooManyRequests, headers: map[string]string{"X-RateLimit-Reset": strconv.FormatInt(now.Add(90*time.Second).Unix(), 10)}, outUntil: 90 * time.Second, reset: 90 * time.Second},
+		{name: "capped", status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "86400"}, outUntil: maxKeyCooldown},
+		{name: "requests left", status: http.StatusOK, headers: map[string]string{"X-RateLimit-Remaining": "3", "X-RateLimit-Reset": "300"}, reset: 300 * time.Second, usable: true},
+		{name: "no requests left", status: http.StatusOK, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "300"}, reset: 300 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newKeyPool("test-api-key")
+			k := p.keys[0]
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for name, value := range tt.headers {
+				resp.Header.Set(name, value)
+			}
+			p.record(k, resp, now)
+
+			if got := k.outUntil.Sub(now); tt.outUntil != 0 && got != tt.outUntil {
+				t.Errorf("key is out of rotation for %s, want %s", got, tt.outUntil)
+			}
+			if got := k.reset.Sub(now); tt.reset != 0 && got != tt.reset {
+				t.Errorf("key resets in %s, want %s", got, tt.reset)
+			}
+			if got := k.usable(now); got != tt.usable {
+				t.Errorf("key is usable: %v, want %v", got, tt.usable)
+			}
+			if _, err := p.pick(now); (err == nil) != tt.usable {
+				t.Errorf("got error %v picking the only key", err)
+			}
+			// every key is back in rotation once it's out of rotation and reset are over
+			later := now.Add(maxKeyCooldown)
+			if !k.usable(later) {
+				t.Errorf("key isn't usable %s later", maxKeyCooldown)
+			}
+		})
+	}
+}
+
+func TestPickDoesNotUseRateLimit(t *testing.T) {
+	p := newKeyPool("test-api-key")
+	p.set([]string{"test-api-key", "other-key"})
+	p.setRateLimit(60, 1)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		k, err := p.pick(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := p.keys[i%2]; k != want {
+			t.Errorf("pick #%d: got key %s, want %s as keys are picked in turn", i+1, k.id, want.id)
+		}
+		p.status(now)
+		p.budget(now)
+	}
+	for _, k := range p.keys {
+		if tokens := k.limiter.TokensAt(now); tokens != 1 {
+			t.Errorf("key %s has %g tokens left, want all of its burst", k.id, tokens)
+		}
+	}
+
+	// a key whose limiter lets a request through sooner is picked first
+	p.keys[0].limiter.AllowN(now, 1)
+	for i := 0; i < 2; i++ {
+		if k, _ := p.pick(now); k != p.keys[1] {
+			t.Errorf("got key %s, want the one with tokens left", k.id)
+		}
+	}
+}
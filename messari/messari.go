@@ -2,26 +2,84 @@ package messari
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/jinzhu/copier"
 	"github.com/sirupsen/logrus"
+	"github.com/ultd/messari-server/logging"
 )
 
 // Client struct is a struct which holds data related to making API requests against Client's API
 type Client struct {
-	httpClient *http.Client
-	baseURL    *url.URL
-	apiKey     string
+	httpClient  *http.Client
+	baseURL     *url.URL
+	keys        *keyPool
+	concurrency int
+	breaker     *breaker
+	stats       *callStats
+	callers     *callerKeys
+	observer    Observer
+}
+
+// Option is a func which configures a Client in New
+type Option func(*Client)
+
+// WithRateLimit func returns an Option which limits the Client to requestsPerMinute requests
+// against Messari's API with each of its API keys, allowing bursts of up to burst requests
+func WithRateLimit(requestsPerMinute float64, burst int) Option {
+	return func(m *Client) {
+		m.keys.setRateLimit(requestsPerMinute, burst)
+	}
+}
+
+// WithConcurrency func returns an Option which sets how many requests batch calls
+// (ie. GetAssetsMetricsBatch) make at the same time
+func WithConcurrency(n int) Option {
+	return func(m *Client) {
+		if n > 0 {
+			m.concurrency = n
+		}
+	}
+}
+
+// WithBaseURL func returns an Option which points the Client at baseURL instead of Messari's API
+// (ie. a proxy or a mock server)
+func WithBaseURL(baseURL *url.URL) Option {
+	return func(m *Client) {
+		if baseURL != nil {
+			m.baseURL = baseURL
+		}
+	}
+}
+
+// WithTimeout func returns an Option which limits how long a single request against Messari's API
+// can take, including reading its response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *Client) {
+		m.httpClient.Timeout = timeout
+	}
+}
+
+// WithCircuitBreaker func returns an Option which makes the Client stop making requests for cooldown
+// after threshold requests in a row failed, returning ErrCircuitOpen instead
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(m *Client) {
+		if threshold > 0 {
+			m.breaker = newBreaker(threshold, cooldown)
+		}
+	}
 }
 
 // New func returns an instance of a Messari
-func New(apiKey string) *Client {
+func New(apiKey string, options ...Option) *Client {
 	if apiKey == "" {
 		panic("need apiKey in order to create new MessariClient!")
 	}
@@ -32,15 +90,42 @@ func New(apiKey string) *Client {
 			Scheme: "https",
 			Host:   "data.messari.io",
 		},
-		apiKey: apiKey,
+		keys:        newKeyPool(apiKey),
+		concurrency: 4,
+		breaker:     newBreaker(5, 30*time.Second),
+		stats:       newCallStats(),
+		callers:     newCallerKeys(),
+		observer:    nopObserver{},
+	}
+
+	for _, option := range options {
+		option(m)
 	}
 
 	return m
 }
 
-func (m *Client) buildURL(path string) string {
-	url := m.baseURL.ResolveReference(&url.URL{Path: path})
-	return url.String()
+// SetRateLimit func changes the rate limit set by WithRateLimit while the Client is in use
+func (m *Client) SetRateLimit(requestsPerMinute float64, burst int) {
+	m.keys.setRateLimit(requestsPerMinute, burst)
+}
+
+// buildURL func returns the URL of path, which must be escaped already (see assetURLPath)
+func (m *Client) buildURL(path string) (string, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("could not parse path %s: %w", path, err)
+	}
+	return m.baseURL.ResolveReference(ref).String(), nil
+}
+
+// assetURLPath func returns the path of Messari's API for symbolOrSlug followed by suffix. The symbol
+// or slug is escaped so it can't make the path reach outside of /api/v1/assets.
+func assetURLPath(symbolOrSlug string, suffix string) (string, error) {
+	if symbolOrSlug == "" || symbolOrSlug == "." || symbolOrSlug == ".." {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAsset, symbolOrSlug)
+	}
+	return "/api/v1/assets/" + url.PathEscape(symbolOrSlug) + suffix, nil
 }
 
 func (m *Client) setRequestQuery(req *http.Request, query map[string][]string) {
@@ -59,20 +144,121 @@ func (m *Client) setRequestHeaders(req *http.Request, headers map[string]string)
 	}
 }
 
-func (m *Client) request(method string, path string, body interface{}, query map[string][]string) (*http.Response, error) {
+// request func makes a request against Messari's API once the circuit breaker and the rate limit
+// allow it, rotating to another of the Client's API keys if Messari rejects or rate limits one
+func (m *Client) request(ctx context.Context, method string, path string, body interface{}, query map[string][]string) (*http.Response, error) {
+	endpoint := endpointOf(path)
+	ctx, span := startSpan(ctx, method, endpoint, path, query)
+	defer span.End()
+
+	// the breaker counts each request once however many attempts it took, and requests made with a
+	// caller's API key (see WithCallerKey) not at all
+	b := m.breaker
+	if callerKeyOf(ctx) != "" {
+		b = nil
+	}
+	if b != nil {
+		if err := b.allow(); err != nil {
+			endSpan(span, 0, nil, err)
+			return nil, err
+		}
+	}
+
+	// a request rejected or rate limited with one of the Client's API keys is made again right away
+	// with another one
+	for attempt := 0; ; attempt++ {
+		resp, sent, err := m.attempt(ctx, endpoint, method, path, body, query)
+		recordAttempt(span, attempt, resp, err)
+		if !m.canRotate(ctx, resp, attempt) {
+			endSpan(span, attempt, resp, err)
+			settle(ctx, b, resp, err, sent)
+			return resp, err
+		}
+		resp.Body.Close()
+		logging.From(ctx).Debugf("Messari responded %d to a request to %s, making it again with another API key", resp.StatusCode, endpoint)
+		m.observer.ObserveRotation(endpoint, resp.StatusCode)
+	}
+}
+
+// settle func records on b how a request went, sent being whether its last attempt was made
+// against Messari's API at all. b may be nil.
+func settle(ctx context.Context, b *breaker, resp *http.Response, err error, sent bool) {
+	if b == nil {
+		return
+	}
+	switch {
+	case !sent, err != nil && ctx.Err() != nil:
+		// the caller gave up or the request never reached Messari, which says nothing about its API
+		b.cancel()
+	case resp != nil && resp.StatusCode == http.StatusTooManyRequests:
+		// the key is taken out of rotation instead, the other keys may still be fine
+		b.cancel()
+	default:
+		b.record(err != nil || resp.StatusCode >= 500)
+	}
+}
+
+// attempt func makes a single attempt of a request, recording how it went. sent is false when the
+// attempt failed before a request was made (ie. waiting for the rate limit).
+func (m *Client) attempt(ctx context.Context, endpoint string, method string, path string, body interface{}, query map[string][]string) (resp *http.Response, sent bool, err error) {
+	cred, err := m.credentialsFor(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := cred.limiter.Wait(ctx); err != nil {
+		if ctx.Err() == nil {
+			// the limiter gives up early when the wait would outlast ctx's deadline
+			return nil, false, fmt.Errorf("could not wait for rate limit: %v: %w", err, context.DeadlineExceeded)
+		}
+		return nil, false, fmt.Errorf("could not wait for rate limit: %w", err)
+	}
+
+	start := time.Now()
+	resp, err = m.do(ctx, cred.apiKey, method, path, body, query)
+	latency := time.Since(start)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	// 4xx responses other than 429 (ie. an unknown asset) are the caller's fault, not Messari's
+	failed := err != nil || status >= 500 || status == http.StatusTooManyRequests
+	if cred.key != nil && (err == nil || ctx.Err() == nil) {
+		m.keys.record(cred.key, resp, time.Now())
+	}
+	cred.stats.record(endpoint, latency, status, failed)
+	entry := logging.From(ctx).WithFields(logrus.Fields{
+		"endpoint":    endpoint,
+		"messari_key": cred.name,
+		"status":      status,
+		"latency_ms":  float64(latency.Microseconds()) / 1000,
+	})
+	if failed {
+		entry.WithError(err).Warn("request to Messari failed")
+	} else {
+		entry.Debug("request to Messari done")
+	}
+	m.observer.ObserveRequest(endpoint, status, latency)
+	return resp, true, err
+}
+
+func (m *Client) do(ctx context.Context, apiKey string, method string, path string, body interface{}, query map[string][]string) (*http.Response, error) {
 	if method == http.MethodGet {
-		reqURL := m.buildURL(path)
-		req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+		reqURL, err := m.buildURL(path)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 		if err != nil {
 			return nil, fmt.Errorf("could not make GET request: %w", err)
 		}
 		m.setRequestHeaders(req, map[string]string{
-			"x-messari-api-key": m.apiKey,
+			"x-messari-api-key": apiKey,
 		})
 		if query != nil {
 			m.setRequestQuery(req, query)
 		}
-		logrus.Debugf("making GET request to %s", req.URL)
+		logging.From(ctx).WithField("url", req.URL.String()).Debug("making GET request to Messari")
 		resp, err := m.httpClient.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("could not do GET request: %w", err)
@@ -88,18 +274,21 @@ func (m *Client) request(method string, path string, body interface{}, query map
 				return nil, fmt.Errorf("could not encode body to JSON: %w", err)
 			}
 		}
-		reqURL := m.buildURL(path)
-		req, err := http.NewRequest(http.MethodGet, reqURL, buf)
+		reqURL, err := m.buildURL(path)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, buf)
 		if err != nil {
 			return nil, fmt.Errorf("could not make POST request: %w", err)
 		}
 		m.setRequestHeaders(req, map[string]string{
-			"x-messari-api-key": m.apiKey,
+			"x-messari-api-key": apiKey,
 		})
 		if query != nil {
 			m.setRequestQuery(req, query)
 		}
-		logrus.Debugf("making POST request to %s", req.URL)
+		logging.From(ctx).WithField("url", req.URL.String()).Debug("making POST request to Messari")
 		resp, err := m.httpClient.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("could not do POST request: %w", err)
@@ -121,7 +310,7 @@ type GetAllAssetsOptions struct {
 
 // GetAllAssets function gets all assets from Messari's API. Accepts a fields []string which
 // indicates which fields to return for each Asset. Pass nil if you need all.
-func (m *Client) GetAllAssets(options *GetAllAssetsOptions) (*GetAllAssetsResp, error) {
+func (m *Client) GetAllAssets(ctx context.Context, options *GetAllAssetsOptions) (*GetAllAssetsResp, error) {
 
 	// Default Options
 	opts := &GetAllAssetsOptions{
@@ -158,7 +347,7 @@ func (m *Client) GetAllAssets(options *GetAllAssetsOptions) (*GetAllAssetsResp,
 		query["sort"] = []string{*opts.Sort}
 	}
 
-	resp, err := m.request(http.MethodGet, "/api/v2/assets", nil, query)
+	resp, err := m.request(ctx, http.MethodGet, "/api/v2/assets", nil, query)
 	if err != nil {
 		return nil, fmt.Errorf("could not make request: %w", err)
 	}
@@ -170,7 +359,7 @@ func (m *Client) GetAllAssets(options *GetAllAssetsOptions) (*GetAllAssetsResp,
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("server returned Status %d instead of 200", resp.StatusCode)
+		return nil, newAPIError(endpointOf(resp.Request.URL.Path), resp)
 	}
 
 	var assetsResp GetAllAssetsResp
@@ -187,7 +376,7 @@ type GetAssetOptions struct {
 }
 
 // GetAsset func fetches basic metadata of a given asset symbol or slug
-func (m *Client) GetAsset(symbolOrSlug string, options *GetAssetOptions) (*GetAssetResp, error) {
+func (m *Client) GetAsset(ctx context.Context, symbolOrSlug string, options *GetAssetOptions) (*GetAssetResp, error) {
 	// default options
 	opts := &GetAssetOptions{
 		Fields: nil,
@@ -204,14 +393,18 @@ func (m *Client) GetAsset(symbolOrSlug string, options *GetAssetOptions) (*GetAs
 		query["fields"] = opts.Fields
 	}
 
-	resp, err := m.request(http.MethodGet, fmt.Sprintf("/api/v1/assets/%s", symbolOrSlug), nil, query)
+	path, err := assetURLPath(symbolOrSlug, "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.request(ctx, http.MethodGet, path, nil, query)
 	if err != nil {
 		return nil, fmt.Errorf("could not make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("server returned Status %d instead of 200", resp.StatusCode)
+		return nil, newAPIError(endpointOf(resp.Request.URL.Path), resp)
 	}
 
 	var assetResp GetAssetResp
@@ -229,7 +422,7 @@ type GetAssetMetricsOptions struct {
 }
 
 // GetAssetMetrics func returns an asset's metrics given a symbol or slug
-func (m *Client) GetAssetMetrics(symbolOrSlug string, options *GetAssetMetricsOptions) (*GetAssetMetricsResp, error) {
+func (m *Client) GetAssetMetrics(ctx context.Context, symbolOrSlug string, options *GetAssetMetricsOptions) (*GetAssetMetricsResp, error) {
 	// default options
 	opts := &GetAssetOptions{
 		Fields: nil,
@@ -246,14 +439,18 @@ func (m *Client) GetAssetMetrics(symbolOrSlug string, options *GetAssetMetricsOp
 		query["fields"] = opts.Fields
 	}
 
-	resp, err := m.request(http.MethodGet, fmt.Sprintf("/api/v1/assets/%s/metrics", symbolOrSlug), nil, query)
+	path, err := assetURLPath(symbolOrSlug, "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.request(ctx, http.MethodGet, path, nil, query)
 	if err != nil {
 		return nil, fmt.Errorf("could not make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("server returned Status %d instead of 200", resp.StatusCode)
+		return nil, newAPIError(endpointOf(resp.Request.URL.Path), resp)
 	}
 
 	var assetMetricsResp GetAssetMetricsResp
@@ -264,6 +461,40 @@ func (m *Client) GetAssetMetrics(symbolOrSlug string, options *GetAssetMetricsOp
 	return &assetMetricsResp, nil
 }
 
+// AssetMetricsResult struct holds the outcome of fetching a single asset's metrics in a batch
+type AssetMetricsResult struct {
+	SymbolOrSlug string
+	Data         *AssetMetricsMetadata
+	Err          error
+}
+
+// GetAssetsMetricsBatch func fetches the metrics of every given symbol or slug concurrently,
+// while still respecting the Client's rate limit. Results are returned in the same order as
+// symbolsOrSlugs, with an Err set for each asset which could not be fetched.
+func (m *Client) GetAssetsMetricsBatch(ctx context.Context, symbolsOrSlugs []string, options *GetAssetMetricsOptions) []AssetMetricsResult {
+	results := make([]AssetMetricsResult, len(symbolsOrSlugs))
+	sem := make(chan struct{}, m.concurrency)
+	var wg sync.WaitGroup
+	for i, symbolOrSlug := range symbolsOrSlugs {
+		wg.Add(1)
+		go func(i int, symbolOrSlug string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = AssetMetricsResult{SymbolOrSlug: symbolOrSlug}
+			resp, err := m.GetAssetMetrics(ctx, symbolOrSlug, options)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+			results[i].Data = &resp.Data
+		}(i, symbolOrSlug)
+	}
+	wg.Wait()
+	return results
+}
+
 // GetAssetMetricsAggregateOptions struct holds option fields for GetAssetMetricsAggregate func
 type GetAssetMetricsAggregateOptions struct {
 	Tags   []string
@@ -0,0 +1,106 @@
+package messari
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient func returns a Client of a test server responding with handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, options ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options = append([]Option{WithBaseURL(u), WithRateLimit(6000, 100)}, options...)
+	return New("test-api-key", options...)
+}
+
+func TestBreakerCountsRequestsOnce(t *testing.T) {
+	var attempts int64
+	m := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&attempts, 1)
+		// the first key is rate limited, so each request is made again with the second one
+		if r.Header.Get("x-messari-api-key") == "test-api-key" {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}, WithAPIKeys("other-key"), WithCircuitBreaker(2, time.Minute))
+
+	if _, err := m.GetAsset(context.Background(), "btc", nil); err == nil {
+		t.Fatal("got no error for a 500")
+	}
+	if got := atomic.LoadInt64(&attempts); got != 2 {
+		t.Fatalf("got %d attempts, want 2", got)
+	}
+	if state := m.BreakerState(); state != BreakerClosed {
+		t.Fatalf("breaker is %s after 1 failed request, want closed", state)
+	}
+
+	if _, err := m.GetAsset(context.Background(), "btc", nil); err == nil {
+		t.Fatal("got no error for a 500")
+	}
+	if state := m.BreakerState(); state != BreakerOpen {
+		t.Fatalf("breaker is %s after 2 failed requests, want open", state)
+	}
+}
+
+func TestRequestsAreNotRetried(t *testing.T) {
+	var attempts int64
+	m := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}, WithAPIKeys("other-key"))
+	if _, err := m.GetAsset(context.Background(), "btc", nil); err == nil {
+		t.Fatal("got no error for a 502")
+	}
+	if got := atomic.LoadInt64(&attempts); got != 1 {
+		t.Fatalf("got %d attempts, want 1", got)
+	}
+}
+
+func TestAssetPathIsEscaped(t *testing.T) {
+	var paths []string
+	m := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		w.Write([]byte("{}"))
+	})
+
+	tests := []struct {
+		symbolOrSlug string
+		path         string
+	}{
+		{symbolOrSlug: "btc", path: "/api/v1/assets/btc/metrics?"},
+		{symbolOrSlug: "../../../v2/assets?x=", path: "/api/v1/assets/..%2F..%2F..%2Fv2%2Fassets%3Fx=/metrics?"},
+		{symbolOrSlug: "a#b", path: "/api/v1/assets/a%23b/metrics?"},
+	}
+	for _, tt := range tests {
+		paths = nil
+		if _, err := m.GetAssetMetrics(context.Background(), tt.symbolOrSlug, nil); err != nil {
+			t.Fatalf("%q: %v", tt.symbolOrSlug, err)
+		}
+		if len(paths) != 1 || paths[0] != tt.path {
+			t.Errorf("%q: got requests to %v, want %s", tt.symbolOrSlug, paths, tt.path)
+		}
+	}
+
+	paths = nil
+	for _, symbolOrSlug := range []string{"", ".", ".."} {
+		if _, err := m.GetAsset(context.Background(), symbolOrSlug, nil); !errors.Is(err, ErrInvalidAsset) {
+			t.Errorf("%q: got error %v, want ErrInvalidAsset", symbolOrSlug, err)
+		}
+	}
+	if len(paths) != 0 {
+		t.Errorf("got requests to %v for invalid assets", paths)
+	}
+}
@@ -0,0 +1,27 @@
+package messari
+
+import "time"
+
+// Observer is notified of every request the Client makes against Messari's API (ie. to export metrics)
+type Observer interface {
+	// ObserveRequest is called after each attempt of a request to endpoint (ie. "/api/v1/assets/:asset/metrics").
+	// status is 0 if the attempt failed without a response.
+	ObserveRequest(endpoint string, status int, latency time.Duration)
+	// ObserveRotation is called before a request to endpoint which got status (401 or 429) with one of
+	// the Client's API keys is made again with another one
+	ObserveRotation(endpoint string, status int)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) ObserveRequest(string, int, time.Duration) {}
+func (nopObserver) ObserveRotation(string, int)               {}
+
+// WithObserver func returns an Option which notifies o of every request the Client makes
+func WithObserver(o Observer) Option {
+	return func(m *Client) {
+		if o != nil {
+			m.observer = o
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package messari
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// callerIdleTTL is how long the rate limiter of a caller's API key is kept after its last request
+const callerIdleTTL = 10 * time.Minute
+
+type callerKeyCtxKey struct{}
+
+// WithCallerKey func returns a copy of ctx whose requests are made with apiKey, the caller's own API
+// key, rather than with the Client's. Those requests are rate limited per caller key, reported apart
+// from the Client's own in Status and don't count towards its circuit breaker, so a caller's used
+// up plan can't affect other requests. Only the requests themselves are kept apart, the Client has no
+// cache to account per caller key.
+func WithCallerKey(ctx context.Context, apiKey string) context.Context {
+	if apiKey == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, callerKeyCtxKey{}, apiKey)
+}
+
+// callerKeyOf func returns the caller's API key set on ctx by WithCallerKey, "" if there's none
+func callerKeyOf(ctx context.Context) string {
+	apiKey, _ := ctx.Value(callerKeyCtxKey{}).(string)
+	return apiKey
+}
+
+// WithPassthroughRateLimit func returns an Option which limits the requests made with each caller's
+// API key (see WithCallerKey) to requestsPerMinute, allowing bursts of up to burst requests
+func WithPassthroughRateLimit(requestsPerMinute float64, burst int) Option {
+	return func(m *Client) {
+		m.callers.setRateLimit(requestsPerMinute, burst)
+	}
+}
+
+// SetPassthroughRateLimit func changes the rate limit set by WithPassthroughRateLimit while the
+// Client is in use
+func (m *Client) SetPassthroughRateLimit(requestsPerMinute float64, burst int) {
+	m.callers.setRateLimit(requestsPerMinute, burst)
+}
+
+// credentials struct is what a single request is made with
+type credentials struct {
+	// name tells the API keys apart in logs without giving them away
+	name    string
+	apiKey  string
+	limiter *rate.Limiter
+	stats   *callStats
+	// key is nil when the request isn't made with one of the Client's own API keys
+	key *poolKey
+}
+
+// credentialsFor func returns the credentials of a request made with ctx, ErrNoAPIKey if it would be
+// made with one of the Client's API keys but they're all out of rotation
+func (m *Client) credentialsFor(ctx context.Context) (credentials, error) {
+	now := time.Now()
+	if apiKey := callerKeyOf(ctx); apiKey != "" {
+		return credentials{name: "caller", apiKey: apiKey, limiter: m.callers.limiter(apiKey, now), stats: m.callers.stats}, nil
+	}
+	k, err := m.keys.pick(now)
+	if err != nil {
+		return credentials{}, err
+	}
+	return credentials{name: k.id, apiKey: k.apiKey, limiter: k.limiter, stats: m.stats, key: k}, nil
+}
+
+// callerLimiter struct is the rate limiter of a caller's API key and when it was last used
+type callerLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// callerKeys struct holds the rate limiters of the callers' API keys and the stats of their requests
+type callerKeys struct {
+	mu                sync.Mutex
+	requestsPerMinute float64
+	burst             int
+	// limiters are by hash of the API key, so the keys aren't kept once their requests are done
+	limiters  map[[sha256.Size]byte]*callerLimiter
+	lastSweep time.Time
+	stats     *callStats
+}
+
+func newCallerKeys() *callerKeys {
+	return &callerKeys{
+		// Messari allows 30 requests per minute with an API key
+		requestsPerMinute: 30,
+		burst:             5,
+		limiters:          map[[sha256.Size]byte]*callerLimiter{},
+		stats:             newCallStats(),
+	}
+}
+
+func (c *callerKeys) setRateLimit(requestsPerMinute float64, burst int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requestsPerMinute, c.burst = requestsPerMinute, burst
+	for _, l := range c.limiters {
+		l.limiter.SetLimit(rate.Limit(requestsPerMinute / 60))
+		l.limiter.SetBurst(burst)
+	}
+}
+
+// limiter func returns the rate limiter of apiKey, forgetting those which weren't used lately
+func (c *callerKeys) limiter(apiKey string, now time.Time) *rate.Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) > callerIdleTTL {
+		c.lastSweep = now
+		for h, l := range c.limiters {
+			if now.Sub(l.lastUsed) > callerIdleTTL {
+				delete(c.limiters, h)
+			}
+		}
+	}
+
+	h := sha256.Sum256([]byte(apiKey))
+	l, ok := c.limiters[h]
+	if !ok {
+		l = &callerLimiter{limiter: rate.NewLimiter(rate.Limit(c.requestsPerMinute/60), c.burst)}
+		c.limiters[h] = l
+	}
+	l.lastUsed = now
+	return l.limiter
+}
+
+// PassthroughStatus struct reports how the requests made with callers' API keys have been going
+type PassthroughStatus struct {
+	// Keys is how many callers' API keys made requests lately
+	Keys      int              `json:"keys"`
+	Endpoints []EndpointStatus `json:"endpoints"`
+}
+
+func (c *callerKeys) status() PassthroughStatus {
+	c.mu.Lock()
+	keys := len(c.limiters)
+	c.mu.Unlock()
+	return PassthroughStatus{Keys: keys, Endpoints: c.stats.status()}
+}
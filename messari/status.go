@@ -0,0 +1,169 @@
+package messari
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// latencyWindow is how many of the latest requests' latencies are kept per endpoint
+const latencyWindow = 512
+
+// assetPath matches the asset symbol or slug in paths of Messari's API, so requests for different
+// assets are counted under the same endpoint
+var assetPath = regexp.MustCompile(`^(/api/v\d+/assets)/[^/]+`)
+
+// endpointOf func returns the endpoint path requested in path (ie. "/api/v1/assets/:asset/metrics")
+func endpointOf(path string) string {
+	return assetPath.ReplaceAllString(path, "$1/:asset")
+}
+
+// ClientStatus struct reports how the Client's requests against Messari's API have been going
+type ClientStatus struct {
+	Breaker   string           `json:"breaker"`
+	RateLimit RateBudget       `json:"rateLimit"`
+	Endpoints []EndpointStatus `json:"endpoints"`
+	// Keys reports the requests made with each of the Client's own API keys
+	Keys []KeyStatus `json:"keys"`
+	// Passthrough reports the requests made with callers' own API keys
+	Passthrough PassthroughStatus `json:"passthrough"`
+}
+
+// RateBudget struct is the Client's rate limit and how many requests can be made right away, summed
+// over its API keys which are in rotation
+type RateBudget struct {
+	RequestsPerMinute float64 `json:"requestsPerMinute"`
+	Burst             int     `json:"burst"`
+	Available         float64 `json:"available"`
+}
+
+// EndpointStatus struct holds the requests made to an endpoint of Messari's API
+type EndpointStatus struct {
+	Endpoint string `json:"endpoint"`
+	Requests int64  `json:"requests"`
+	// Errors counts requests which failed or got a 5xx or 429 response
+	Errors int64 `json:"errors"`
+	// LastStatus is the status code of the latest response, 0 if the request failed without one
+	LastStatus  int        `json:"lastStatus"`
+	LastSuccess *time.Time `json:"lastSuccess"`
+	Latency     Latency    `json:"latencyMs"`
+}
+
+// Latency struct holds percentiles of the latest requests' latencies in milliseconds
+type Latency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+type endpointStats struct {
+	requests    int64
+	errors      int64
+	lastStatus  int
+	lastSuccess time.Time
+	// latencies is a ring buffer of the latest latencies, next is where the next one goes
+	latencies []time.Duration
+	next      int
+}
+
+// callStats struct keeps endpointStats of every endpoint requested
+type callStats struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointStats
+}
+
+func newCallStats() *callStats {
+	return &callStats{endpoints: map[string]*endpointStats{}}
+}
+
+func (c *callStats) record(endpoint string, latency time.Duration, status int, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.endpoints[endpoint]
+	if !ok {
+		e = &endpointStats{latencies: make([]time.Duration, 0, latencyWindow)}
+		c.endpoints[endpoint] = e
+	}
+	e.requests++
+	e.lastStatus = status
+	if failed {
+		e.errors++
+	} else {
+		e.lastSuccess = time.Now()
+	}
+	if len(e.latencies) < latencyWindow {
+		e.latencies = append(e.latencies, latency)
+	} else {
+		e.latencies[e.next] = latency
+	}
+	e.next = (e.next + 1) % latencyWindow
+}
+
+func (c *callStats) status() []EndpointStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make([]EndpointStatus, 0, len(c.endpoints))
+	for endpoint, e := range c.endpoints {
+		s := EndpointStatus{
+			Endpoint:   endpoint,
+			Requests:   e.requests,
+			Errors:     e.errors,
+			LastStatus: e.lastStatus,
+			Latency:    percentiles(e.latencies),
+		}
+		if !e.lastSuccess.IsZero() {
+			lastSuccess := e.lastSuccess
+			s.LastSuccess = &lastSuccess
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Endpoint < statuses[j].Endpoint
+	})
+	return statuses
+}
+
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	at := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return float64(sorted[i].Microseconds()) / 1000
+	}
+	return Latency{P50: at(0.5), P90: at(0.9), P99: at(0.99)}
+}
+
+// Status func returns how the Client's requests have been going
+func (m *Client) Status() ClientStatus {
+	now := time.Now()
+	return ClientStatus{
+		Breaker:     m.breaker.State().String(),
+		RateLimit:   m.keys.budget(now),
+		Endpoints:   m.stats.status(),
+		Keys:        m.keys.status(now),
+		Passthrough: m.callers.status(),
+	}
+}
+
+// BreakerState func returns the state of the Client's circuit breaker
+func (m *Client) BreakerState() BreakerState {
+	return m.breaker.State()
+}
+
+// available func returns how many requests l lets through right away
+func available(l *rate.Limiter, now time.Time) float64 {
+	return math.Max(0, math.Floor(l.TokensAt(now)))
+}
@@ -0,0 +1,56 @@
+package messari
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/ultd/messari-server/messari"
+
+// startSpan func starts the span of a request against Messari's API as a child of the span in ctx
+// (ie. the span of the incoming request the call is made for)
+func startSpan(ctx context.Context, method string, endpoint string, path string, query map[string][]string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, fmt.Sprintf("messari %s %s", method, endpoint),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(method),
+			semconv.HTTPRouteKey.String(endpoint),
+			semconv.HTTPTargetKey.String(path),
+			attribute.String("messari.query", url.Values(query).Encode()),
+		),
+	)
+}
+
+// recordAttempt func adds an event for an attempt of the request to its span
+func recordAttempt(span trace.Span, attempt int, resp *http.Response, err error) {
+	attrs := []attribute.KeyValue{attribute.Int("attempt", attempt)}
+	if resp != nil {
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	}
+	span.AddEvent("attempt", trace.WithAttributes(attrs...))
+}
+
+// endSpan func records the outcome of the request's last attempt on its span
+func endSpan(span trace.Span, attempt int, resp *http.Response, err error) {
+	span.SetAttributes(attribute.Int("messari.key_rotations", attempt))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+}
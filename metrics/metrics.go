@@ -0,0 +1,129 @@
+// Package metrics holds the server's Prometheus metrics and exposes them for scraping. The collectors
+// are registered in Prometheus' default registry, so the Go runtime's and the process' metrics are
+// exported along with them.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "messari_server"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Requests handled, by route and status code.",
+	}, []string{"route", "method", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle requests, by route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	httpShed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_shed_total",
+		Help:      "Requests refused with 429 by the inbound limits, by route and reason (rate_limit or concurrency).",
+	}, []string{"route", "reason"})
+
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests made against Messari's API, by endpoint and status code (0 when there was no response).",
+	}, []string{"endpoint", "status"})
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Time taken by requests against Messari's API, by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	upstreamRotations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_key_rotations_total",
+		Help:      "Requests against Messari's API made again with another API key, by endpoint and status which took the key out of rotation.",
+	}, []string{"endpoint", "status"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Lookups in the local caches of Messari's asset universe, by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+	refreshDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cache_refresh_duration_seconds",
+		Help:      "Time taken to refresh the local caches of Messari's asset universe, by cache and result (ok or error).",
+		Buckets:   []float64{1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"cache", "result"})
+)
+
+// Handler func returns the http.Handler serving the metrics to Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest func records a request handled by route (ie. "/api/asset/:symbolOrSlug")
+func ObserveRequest(route string, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// Shed func records a request to route refused by the inbound limits for reason
+func Shed(route string, reason string) {
+	httpShed.WithLabelValues(route, reason).Inc()
+}
+
+// Upstream struct is a messari.Observer recording the requests made against Messari's API
+type Upstream struct{}
+
+// ObserveRequest func records an attempt of a request against endpoint
+func (Upstream) ObserveRequest(endpoint string, status int, latency time.Duration) {
+	upstreamRequests.WithLabelValues(endpoint, strconv.Itoa(status)).Inc()
+	upstreamDuration.WithLabelValues(endpoint).Observe(latency.Seconds())
+}
+
+// ObserveRotation func records a request against endpoint made again with another API key after getting status
+func (Upstream) ObserveRotation(endpoint string, status int) {
+	upstreamRotations.WithLabelValues(endpoint, strconv.Itoa(status)).Inc()
+}
+
+// CacheLookup func records a lookup in cache, which is a hit if the cache could answer it
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// ObserveRefresh func records a refresh of cache which took duration, failing if err isn't nil
+func ObserveRefresh(cache string, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	refreshDuration.WithLabelValues(cache, result).Observe(duration.Seconds())
+}
+
+// CacheAge func exports how long ago cache was last refreshed, updatedAt returning when (zero if never)
+func CacheAge(cache string, updatedAt func() time.Time) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "cache_age_seconds",
+		Help:        "Time since the local caches of Messari's asset universe were last refreshed, -1 if they never were.",
+		ConstLabels: prometheus.Labels{"cache": cache},
+	}, func() float64 {
+		t := updatedAt()
+		if t.IsZero() {
+			return -1
+		}
+		return time.Since(t).Seconds()
+	})
+}
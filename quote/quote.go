@@ -0,0 +1,186 @@
+// Package quote converts the USD figures of assets into other quote currencies: BTC and ETH using
+// Messari's own prices, and fiat currencies using a table of exchange rates loaded from a file.
+package quote
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ultd/messari-server/messari"
+	"gopkg.in/yaml.v2"
+)
+
+// Currencies which can always be quoted in, without an exchange rate table
+const (
+	USD = "USD"
+	BTC = "BTC"
+	ETH = "ETH"
+)
+
+// Table struct is a table of fiat exchange rates, loaded from a YAML file such as:
+//
+//	# units of each currency per 1 USD
+//	rates:
+//	  EUR: 0.92
+//	  GBP: 0.79
+type Table struct {
+	Rates map[string]float64 `yaml:"rates"`
+}
+
+// Load func reads the Table from the YAML file at path
+func Load(path string) (*Table, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read exchange rates: %w", err)
+	}
+	var t Table
+	if err := yaml.UnmarshalStrict(b, &t); err != nil {
+		return nil, fmt.Errorf("could not parse exchange rates in %s: %w", path, err)
+	}
+	rates := make(map[string]float64, len(t.Rates))
+	for currency, rate := range t.Rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("exchange rate of %s in %s must be positive", currency, path)
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	t.Rates = rates
+	return &t, nil
+}
+
+// Supports func returns whether currency can be quoted in. A nil Table only supports USD, BTC and ETH.
+func (t *Table) Supports(currency string) bool {
+	switch currency = strings.ToUpper(currency); currency {
+	case USD, BTC, ETH:
+		return true
+	}
+	if t == nil {
+		return false
+	}
+	_, ok := t.Rates[currency]
+	return ok
+}
+
+// Prices struct holds the USD prices of the crypto currencies which can be quoted in
+type Prices struct {
+	BTC float64
+	ETH float64
+}
+
+// PricesOf func returns the prices of BTC and ETH implied by an asset's prices in USD, BTC and ETH
+func PricesOf(md messari.MarketData) Prices {
+	var p Prices
+	if md.PriceBtc > 0 {
+		p.BTC = md.PriceUsd / md.PriceBtc
+	}
+	if md.PriceEth > 0 {
+		p.ETH = md.PriceUsd / md.PriceEth
+	}
+	return p
+}
+
+// ReferencePrices func returns the prices of BTC and ETH from the bitcoin and ethereum assets in assets
+func ReferencePrices(assets []messari.Asset) Prices {
+	var p Prices
+	for _, asset := range assets {
+		switch asset.Slug {
+		case "bitcoin":
+			p.BTC = asset.Metrics.MarketData.PriceUsd
+		case "ethereum":
+			p.ETH = asset.Metrics.MarketData.PriceUsd
+		}
+	}
+	return p
+}
+
+// Converter struct converts USD figures to Currency
+type Converter struct {
+	Currency string
+	rate     float64
+}
+
+// Converter func returns a Converter to currency, using prices for BTC and ETH
+func (t *Table) Converter(currency string, prices Prices) (*Converter, error) {
+	c := &Converter{Currency: strings.ToUpper(currency)}
+	switch c.Currency {
+	case USD:
+		c.rate = 1
+	case BTC:
+		if prices.BTC <= 0 {
+			return nil, fmt.Errorf("no BTC price to convert with")
+		}
+		c.rate = 1 / prices.BTC
+	case ETH:
+		if prices.ETH <= 0 {
+			return nil, fmt.Errorf("no ETH price to convert with")
+		}
+		c.rate = 1 / prices.ETH
+	default:
+		if !t.Supports(c.Currency) {
+			return nil, fmt.Errorf("no exchange rate for %s", c.Currency)
+		}
+		c.rate = t.Rates[c.Currency]
+	}
+	return c, nil
+}
+
+// Convert func converts an amount in USD
+func (c *Converter) Convert(usd float64) float64 {
+	return usd * c.rate
+}
+
+func (c *Converter) convertPtr(usd *float64) *float64 {
+	if usd == nil {
+		return nil
+	}
+	v := c.Convert(*usd)
+	return &v
+}
+
+// Asset func returns a copy of asset with its metrics converted, see Metrics
+func (c *Converter) Asset(asset messari.Asset) messari.Asset {
+	asset.Metrics = c.Metrics(asset.Metrics)
+	return asset
+}
+
+// Metrics func returns a copy of m with its prices, market caps and volumes converted. The fields keep their
+// Usd names. When quoting in BTC or ETH, the 24 hour percent change is replaced by the one against BTC or ETH.
+func (c *Converter) Metrics(m messari.Metrics) messari.Metrics {
+	if c.Currency == USD {
+		return m
+	}
+	md := &m.MarketData
+	md.PriceUsd = c.Convert(md.PriceUsd)
+	md.VolumeLast24Hours = c.Convert(md.VolumeLast24Hours)
+	md.RealVolumeLast24Hours = c.Convert(md.RealVolumeLast24Hours)
+	md.OhlcvLast1Hour = c.ohlcv(md.OhlcvLast1Hour)
+	md.OhlcvLast24Hour = c.ohlcv(md.OhlcvLast24Hour)
+	switch c.Currency {
+	case BTC:
+		md.PercentChangeUsdLast24Hours = md.PercentChangeBtcLast24Hours
+	case ETH:
+		md.PercentChangeUsdLast24Hours = md.PercentChangeEthLast24Hours
+	}
+
+	mc := &m.Marketcap
+	mc.CurrentMarketcapUsd = c.Convert(mc.CurrentMarketcapUsd)
+	mc.Y2050MarketcapUsd = c.convertPtr(mc.Y2050MarketcapUsd)
+	mc.YPlus10MarketcapUsd = c.convertPtr(mc.YPlus10MarketcapUsd)
+	mc.LiquidMarketcapUsd = c.convertPtr(mc.LiquidMarketcapUsd)
+	mc.RealizedMarketcapUsd = c.convertPtr(mc.RealizedMarketcapUsd)
+
+	m.AllTimeHigh.Price = c.convertPtr(m.AllTimeHigh.Price)
+	m.CycleLow.Price = c.convertPtr(m.CycleLow.Price)
+	return m
+}
+
+func (c *Converter) ohlcv(o messari.OHLCVLastHour) messari.OHLCVLastHour {
+	return messari.OHLCVLastHour{
+		Open:   c.Convert(o.Open),
+		High:   c.Convert(o.High),
+		Low:    c.Convert(o.Low),
+		Close:  c.Convert(o.Close),
+		Volume: c.Convert(o.Volume),
+	}
+}
@@ -0,0 +1,117 @@
+// Package ratelimit limits the requests the server's clients make to it. Each client (an API key, or
+// an IP when requests aren't authenticated) gets its own token bucket, and requests take as many
+// tokens as their route costs so expensive routes use up the budget faster. The routes costing more
+// than a token are also capped in how many are handled at the same time, across all clients.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sweepInterval is how often clients which stopped making requests are forgotten
+const sweepInterval = time.Minute
+
+// client struct is a client's bucket and when it last made a request
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter struct holds the buckets of every client which made a request lately
+type Limiter struct {
+	mu                sync.Mutex
+	requestsPerMinute float64
+	burst             int
+	clients           map[string]*client
+	lastSweep         time.Time
+
+	// maxExpensive is how many expensive requests can be handled at the same time, expensive how many are
+	maxExpensive int
+	expensive    int
+}
+
+// New func returns a Limiter letting each client make requestsPerMinute requests of cost 1 per minute
+// and burst at once, and handling maxExpensive expensive requests at the same time. Clients aren't
+// limited when requestsPerMinute is 0, nor expensive requests when maxExpensive is 0.
+func New(requestsPerMinute float64, burst int, maxExpensive int) *Limiter {
+	return &Limiter{
+		requestsPerMinute: requestsPerMinute,
+		burst:             burst,
+		maxExpensive:      maxExpensive,
+		clients:           map[string]*client{},
+	}
+}
+
+// Set func changes the limits given to New while the Limiter is in use
+func (l *Limiter) Set(requestsPerMinute float64, burst int, maxExpensive int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requestsPerMinute, l.burst, l.maxExpensive = requestsPerMinute, burst, maxExpensive
+	for _, c := range l.clients {
+		c.limiter.SetLimit(rate.Limit(requestsPerMinute / 60))
+		c.limiter.SetBurst(burst)
+	}
+}
+
+// Allow func takes cost tokens from id's bucket at now. It returns false and how long until the bucket
+// holds enough tokens when there aren't enough.
+func (l *Limiter) Allow(id string, cost int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.requestsPerMinute <= 0 {
+		return true, 0
+	}
+	l.sweep(now)
+
+	c, ok := l.clients[id]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(rate.Limit(l.requestsPerMinute/60), l.burst)}
+		l.clients[id] = c
+	}
+	c.lastSeen = now
+
+	if cost > l.burst {
+		// the request could never be allowed, which the config's validation should prevent
+		return false, time.Minute
+	}
+	r := c.limiter.ReserveN(now, cost)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep func forgets the clients whose bucket has been full for a while, l.mu must be held
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	// a bucket is full again once it went unused for as long as refilling burst tokens takes
+	refill := time.Duration(float64(l.burst) / l.requestsPerMinute * float64(time.Minute))
+	for id, c := range l.clients {
+		if now.Sub(c.lastSeen) > refill+sweepInterval {
+			delete(l.clients, id)
+		}
+	}
+}
+
+// AcquireExpensive func reserves a slot for handling an expensive request, returning false if all
+// are taken. The returned func frees the slot once the request is handled.
+func (l *Limiter) AcquireExpensive() (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxExpensive > 0 && l.expensive >= l.maxExpensive {
+		return nil, false
+	}
+	l.expensive++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.expensive--
+	}, true
+}
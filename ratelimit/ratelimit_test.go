@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestAllow(t *testing.T) {
+	l := New(60, 5, 0)
+
+	if ok, _ := l.Allow("a", 5, epoch); !ok {
+		t.Fatal("first request wasn't allowed, want the whole burst available")
+	}
+	ok, retryAfter := l.Allow("a", 2, epoch)
+	if ok {
+		t.Fatal("request was allowed with an empty bucket")
+	}
+	// 60 per minute refills a token a second
+	if retryAfter != 2*time.Second {
+		t.Errorf("got retry after %s, want 2s", retryAfter)
+	}
+	if ok, _ := l.Allow("b", 1, epoch); !ok {
+		t.Error("another client's request wasn't allowed")
+	}
+	// refused requests don't take tokens, so the 2 tokens are there after 2s
+	if ok, _ := l.Allow("a", 2, epoch.Add(2*time.Second)); !ok {
+		t.Error("request wasn't allowed once the bucket refilled")
+	}
+	if ok, retryAfter := l.Allow("a", 6, epoch.Add(time.Hour)); ok || retryAfter != time.Minute {
+		t.Errorf("got %t and retry after %s for a cost over the burst, want false and 1m", ok, retryAfter)
+	}
+}
+
+func TestAllowUnlimited(t *testing.T) {
+	l := New(0, 0, 0)
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a", 10, epoch); !ok {
+			t.Fatalf("request #%d wasn't allowed without a limit", i+1)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l := New(60, 5, 0)
+	l.Allow("idle", 5, epoch)
+	l.Allow("busy", 5, epoch)
+
+	// refilling 5 tokens takes 5s, clients are forgotten a sweep interval after that
+	l.Allow("busy", 1, epoch.Add(sweepInterval))
+	if _, ok := l.clients["idle"]; !ok {
+		t.Fatal("idle client was forgotten before its bucket could be full")
+	}
+	l.Allow("busy", 1, epoch.Add(2*sweepInterval+5*time.Second))
+	if _, ok := l.clients["idle"]; ok {
+		t.Error("idle client wasn't forgotten")
+	}
+	if _, ok := l.clients["busy"]; !ok {
+		t.Error("busy client was forgotten")
+	}
+}
+
+func TestSet(t *testing.T) {
+	// Set changes the buckets as of the current time, so they're used from then on
+	now := time.Now()
+	l := New(60, 5, 1)
+	l.Allow("a", 5, now)
+
+	l.Set(600, 10, 2)
+	// 600 per minute refills 10 tokens a second, up to the new burst
+	if ok, _ := l.Allow("a", 10, now.Add(2*time.Second)); !ok {
+		t.Error("existing client didn't get the new limit")
+	}
+	if ok, _ := l.Allow("b", 10, now); !ok {
+		t.Error("new client didn't get the new burst")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.AcquireExpensive(); !ok {
+			t.Fatalf("expensive request #%d wasn't allowed with the new cap", i+1)
+		}
+	}
+	if _, ok := l.AcquireExpensive(); ok {
+		t.Error("expensive request over the new cap was allowed")
+	}
+}
+
+func TestAcquireExpensive(t *testing.T) {
+	l := New(60, 5, 2)
+	first, ok := l.AcquireExpensive()
+	if !ok {
+		t.Fatal("first expensive request wasn't allowed")
+	}
+	if _, ok := l.AcquireExpensive(); !ok {
+		t.Fatal("second expensive request wasn't allowed")
+	}
+	if _, ok := l.AcquireExpensive(); ok {
+		t.Fatal("expensive request over the cap was allowed")
+	}
+	first()
+	if _, ok := l.AcquireExpensive(); !ok {
+		t.Error("expensive request wasn't allowed once a slot was released")
+	}
+
+	uncapped := New(60, 5, 0)
+	for i := 0; i < 100; i++ {
+		if _, ok := uncapped.AcquireExpensive(); !ok {
+			t.Fatalf("expensive request #%d wasn't allowed without a cap", i+1)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package stats holds the summary statistics used to aggregate asset metrics.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Mean func returns the arithmetic mean of values, 0 if there are none
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Median func returns the median of values, 0 if there are none
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// StdDev func returns the population standard deviation of values, 0 if there are none
+func StdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+// WeightedMean func returns the mean of values where each value counts as much as the weight
+// at the same index. Values without a positive weight are ignored, 0 is returned if none have one.
+func WeightedMean(values, weights []float64) float64 {
+	sum, total := 0.0, 0.0
+	for i, v := range values {
+		if weights[i] <= 0 {
+			continue
+		}
+		sum += v * weights[i]
+		total += weights[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// WeightedMedian func returns the value at which half of the total weight is reached when values
+// are sorted. Values without a positive weight are ignored, 0 is returned if none have one.
+func WeightedMedian(values, weights []float64) float64 {
+	type weighted struct{ value, weight float64 }
+	sorted := make([]weighted, 0, len(values))
+	total := 0.0
+	for i, v := range values {
+		if weights[i] <= 0 {
+			continue
+		}
+		sorted = append(sorted, weighted{v, weights[i]})
+		total += weights[i]
+	}
+	if len(sorted) == 0 {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].value < sorted[j].value })
+	cumulative := 0.0
+	for i, w := range sorted {
+		cumulative += w.weight
+		if cumulative == total/2 && i+1 < len(sorted) {
+			return (w.value + sorted[i+1].value) / 2
+		}
+		if cumulative > total/2 {
+			return w.value
+		}
+	}
+	return sorted[len(sorted)-1].value
+}
+
+// WeightedStdDev func returns the standard deviation of values around their WeightedMean.
+// Values without a positive weight are ignored, 0 is returned if none have one.
+func WeightedStdDev(values, weights []float64) float64 {
+	mean := WeightedMean(values, weights)
+	sum, total := 0.0, 0.0
+	for i, v := range values {
+		if weights[i] <= 0 {
+			continue
+		}
+		sum += weights[i] * (v - mean) * (v - mean)
+		total += weights[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	return math.Sqrt(sum / total)
+}
+
+// PercentileRanks func returns the percentile rank (0-100) of each of values, the percentage of
+// values which are lower, with equal values counting as half lower
+func PercentileRanks(values []float64) []float64 {
+	ranks := make([]float64, len(values))
+	if len(values) == 0 {
+		return ranks
+	}
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	n := float64(len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		rank := (float64(start) + float64(end-start)/2) / n * 100
+		for _, i := range order[start:end] {
+			ranks[i] = rank
+		}
+		start = end
+	}
+	return ranks
+}
@@ -0,0 +1,89 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
+
+func TestUnweighted(t *testing.T) {
+	tests := []struct {
+		name                 string
+		values               []float64
+		mean, median, stddev float64
+	}{
+		{name: "empty"},
+		{name: "single", values: []float64{3}, mean: 3, median: 3},
+		{name: "odd", values: []float64{5, 1, 3}, mean: 3, median: 3, stddev: math.Sqrt(8.0 / 3)},
+		{name: "even", values: []float64{4, 1, 3, 2}, mean: 2.5, median: 2.5, stddev: math.Sqrt(1.25)},
+		{name: "negative", values: []float64{-2, 2}, mean: 0, median: 0, stddev: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mean(tt.values); !near(got, tt.mean) {
+				t.Errorf("got mean %v, want %v", got, tt.mean)
+			}
+			if got := Median(tt.values); !near(got, tt.median) {
+				t.Errorf("got median %v, want %v", got, tt.median)
+			}
+			if got := StdDev(tt.values); !near(got, tt.stddev) {
+				t.Errorf("got standard deviation %v, want %v", got, tt.stddev)
+			}
+		})
+	}
+}
+
+func TestMedianDoesntSortValues(t *testing.T) {
+	values := []float64{3, 1, 2}
+	Median(values)
+	if values[0] != 3 || values[1] != 1 || values[2] != 2 {
+		t.Errorf("values were reordered to %v", values)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	tests := []struct {
+		name                 string
+		values, weights      []float64
+		mean, median, stddev float64
+	}{
+		{name: "empty"},
+		{name: "no positive weight", values: []float64{1, 2}, weights: []float64{0, -1}},
+		{name: "equal weights", values: []float64{1, 2, 3}, weights: []float64{1, 1, 1}, mean: 2, median: 2, stddev: math.Sqrt(2.0 / 3)},
+		{name: "heavy value", values: []float64{1, 10}, weights: []float64{3, 1}, mean: 3.25, median: 1, stddev: math.Sqrt(15.1875)},
+		{name: "half the weight", values: []float64{1, 3}, weights: []float64{2, 2}, mean: 2, median: 2, stddev: 1},
+		{name: "ignored weights", values: []float64{100, 1, 3}, weights: []float64{0, 1, 1}, mean: 2, median: 2, stddev: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WeightedMean(tt.values, tt.weights); !near(got, tt.mean) {
+				t.Errorf("got mean %v, want %v", got, tt.mean)
+			}
+			if got := WeightedMedian(tt.values, tt.weights); !near(got, tt.median) {
+				t.Errorf("got median %v, want %v", got, tt.median)
+			}
+			if got := WeightedStdDev(tt.values, tt.weights); !near(got, tt.stddev) {
+				t.Errorf("got standard deviation %v, want %v", got, tt.stddev)
+			}
+		})
+	}
+}
+
+func TestPercentileRanks(t *testing.T) {
+	got := PercentileRanks([]float64{30, 10, 20, 20})
+	// equal values, the value itself included, count as half lower: 10 is half of 1 value lower out of 4,
+	// each 20 has 10 lower and both 20s as half
+	want := []float64{87.5, 12.5, 50, 50}
+	for i := range want {
+		if !near(got[i], want[i]) {
+			t.Errorf("got ranks %v, want %v", got, want)
+			break
+		}
+	}
+	if got := PercentileRanks(nil); len(got) != 0 {
+		t.Errorf("got %v for no values, want none", got)
+	}
+}
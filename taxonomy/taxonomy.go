@@ -0,0 +1,59 @@
+// Package taxonomy normalizes an asset's sectors, categories and tags. Messari has them both in the
+// profile's GeneralOverview, where Tags is a single comma seperated string, and in the metrics' MiscData
+// as lists, and the two don't always agree.
+package taxonomy
+
+import (
+	"strings"
+
+	"github.com/ultd/messari-server/messari"
+)
+
+// Terms struct holds the normalized sectors, categories and tags of an asset
+type Terms struct {
+	Sectors    []string `json:"sectors"`
+	Categories []string `json:"categories"`
+	Tags       []string `json:"tags"`
+}
+
+// Of func returns the merged sectors, categories and tags of asset from both its profile and its metrics.
+// Multi-value strings are split and duplicates differing only in case or spacing are dropped, keeping the
+// profile's spelling.
+func Of(asset messari.Asset) Terms {
+	overview := asset.Profile.General.Overview
+	misc := asset.Metrics.MiscData
+	return Terms{
+		Sectors:    merge(split(overview.Sector), misc.Sectors),
+		Categories: merge(split(overview.Category), misc.Categories),
+		Tags:       merge(split(overview.Tags), misc.Tags),
+	}
+}
+
+// Key func returns the form of a term used to tell whether two terms are the same
+func Key(term string) string {
+	return strings.ToLower(strings.Join(strings.Fields(term), " "))
+}
+
+func split(s *string) []string {
+	if s == nil {
+		return nil
+	}
+	return strings.Split(*s, ",")
+}
+
+func merge(lists ...[]string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, term := range list {
+			term = strings.Join(strings.Fields(term), " ")
+			key := Key(term)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,163 @@
+package universe
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/metrics"
+)
+
+// ErrNotFound is returned by Directory's Resolve when no asset matches the given key
+var ErrNotFound = errors.New("asset not found in directory")
+
+// AmbiguousError is returned by Directory's Resolve when a symbol or slug matches several assets
+type AmbiguousError struct {
+	Key        string
+	Candidates []Entry
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%s is ambiguous, matches %d assets", e.Key, len(e.Candidates))
+}
+
+// Entry struct holds the identifiers of an asset in the Directory
+type Entry struct {
+	ID           string  `json:"id"`
+	Symbol       string  `json:"symbol,omitempty"`
+	Name         string  `json:"name,omitempty"`
+	Slug         string  `json:"slug,omitempty"`
+	Tagline      string  `json:"tagline,omitempty"`
+	MarketcapUsd float64 `json:"marketcapUsd,omitempty"`
+}
+
+// Directory struct is a local directory of the assets in the Snapshot which resolves symbols, slugs
+// and IDs to canonical asset IDs. The Snapshot only has assets with metrics and a profile, so the
+// others aren't in the Directory and have to be resolved by Messari instead.
+type Directory struct {
+	mu        sync.RWMutex
+	entries   []Entry
+	byID      map[string]int
+	bySlug    map[string]int
+	bySymbol  map[string][]int
+	updatedAt time.Time
+}
+
+// NewDirectory func returns an empty Directory, which is filled by Update (ie. on each refresh of the Snapshot)
+func NewDirectory() *Directory {
+	return &Directory{}
+}
+
+// Update func rebuilds the Directory from assets
+func (d *Directory) Update(assets []messari.Asset) {
+	entries := make([]Entry, 0, len(assets))
+	for _, asset := range assets {
+		if asset.ID == "" {
+			continue
+		}
+		entry := Entry{
+			ID:           asset.ID,
+			Symbol:       asset.Symbol,
+			Name:         asset.Name,
+			Slug:         asset.Slug,
+			MarketcapUsd: asset.Metrics.Marketcap.CurrentMarketcapUsd,
+		}
+		if asset.Profile.General.Overview.Tagline != nil {
+			entry.Tagline = *asset.Profile.General.Overview.Tagline
+		}
+		entries = append(entries, entry)
+	}
+	d.set(entries)
+}
+
+// set func replaces the Directory's assets with entries
+func (d *Directory) set(entries []Entry) {
+	// largest assets first so candidates of an ambiguous key are ordered by relevance
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].MarketcapUsd > entries[j].MarketcapUsd
+	})
+
+	byID := make(map[string]int, len(entries))
+	bySlug := make(map[string]int, len(entries))
+	bySymbol := make(map[string][]int, len(entries))
+	for i, entry := range entries {
+		byID[strings.ToLower(entry.ID)] = i
+		if entry.Slug != "" {
+			bySlug[strings.ToLower(entry.Slug)] = i
+		}
+		if entry.Symbol != "" {
+			symbol := strings.ToLower(entry.Symbol)
+			bySymbol[symbol] = append(bySymbol[symbol], i)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = entries
+	d.byID = byID
+	d.bySlug = bySlug
+	d.bySymbol = bySymbol
+	d.updatedAt = time.Now()
+}
+
+// Resolve func returns the asset identified by key, which may be an ID, slug or symbol. IDs
+// and slugs are unique but symbols are not, and a slug may also be the symbol of other assets
+// (ie. an asset with slug "ren" and another with symbol "REN"). An *AmbiguousError listing
+// every candidate is returned when key matches more than one asset other than by ID.
+func (d *Directory) Resolve(key string) (Entry, error) {
+	k := strings.ToLower(strings.TrimSpace(key))
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	metrics.CacheLookup("directory", d.lookup(k))
+
+	if i, ok := d.byID[k]; ok {
+		return d.entries[i], nil
+	}
+	matches := d.bySymbol[k]
+	if i, ok := d.bySlug[k]; ok {
+		matches = withMatch(matches, i)
+	}
+	switch len(matches) {
+	case 0:
+		return Entry{}, ErrNotFound
+	case 1:
+		return d.entries[matches[0]], nil
+	}
+	candidates := make([]Entry, len(matches))
+	for i, match := range matches {
+		candidates[i] = d.entries[match]
+	}
+	return Entry{}, &AmbiguousError{Key: key, Candidates: candidates}
+}
+
+// withMatch func returns the sorted indexes of matches with i added, unless it's already in there
+func withMatch(matches []int, i int) []int {
+	merged := make([]int, 0, len(matches)+1)
+	merged = append(merged, i)
+	for _, match := range matches {
+		if match != i {
+			merged = append(merged, match)
+		}
+	}
+	sort.Ints(merged)
+	return merged
+}
+
+// lookup func returns whether the Directory knows about k, it has to be called with mu held
+func (d *Directory) lookup(k string) bool {
+	_, id := d.byID[k]
+	_, slug := d.bySlug[k]
+	return id || slug || len(d.bySymbol[k]) > 0
+}
+
+// UpdatedAt func returns when the Directory was last refreshed, zero if it never was
+func (d *Directory) UpdatedAt() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.updatedAt
+}
@@ -0,0 +1,71 @@
+package universe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDirectoryResolve(t *testing.T) {
+	d := NewDirectory()
+	d.set([]Entry{
+		{ID: "id-ren", Symbol: "REN", Slug: "ren", MarketcapUsd: 300},
+		{ID: "id-republic", Symbol: "REP", Slug: "republic", MarketcapUsd: 100},
+		{ID: "id-bitcoin", Symbol: "BTC", Slug: "bitcoin", MarketcapUsd: 1000},
+		{ID: "id-btc-clone", Symbol: "BTC", Slug: "bitcoin-clone", MarketcapUsd: 1},
+		// its symbol is the slug of another asset
+		{ID: "id-wrapped", Symbol: "REPUBLIC", Slug: "wrapped-republic", MarketcapUsd: 200},
+	})
+
+	tests := []struct {
+		key string
+		// want is the ID of the asset key resolves to, candidates the IDs of the assets it could mean
+		want       string
+		candidates []string
+		notFound   bool
+	}{
+		{key: "id-bitcoin", want: "id-bitcoin"},
+		{key: "bitcoin", want: "id-bitcoin"},
+		{key: " Bitcoin-Clone ", want: "id-btc-clone"},
+		{key: "REP", want: "id-republic"},
+		// slug and symbol of the same asset
+		{key: "ren", want: "id-ren"},
+		{key: "btc", candidates: []string{"id-bitcoin", "id-btc-clone"}},
+		{key: "republic", candidates: []string{"id-wrapped", "id-republic"}},
+		{key: "eth", notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			entry, err := d.Resolve(tt.key)
+			switch {
+			case tt.notFound:
+				if err != ErrNotFound {
+					t.Fatalf("got %v and %v, want ErrNotFound", entry, err)
+				}
+			case tt.candidates != nil:
+				var ambiguous *AmbiguousError
+				if !errors.As(err, &ambiguous) {
+					t.Fatalf("got %v and %v, want an AmbiguousError", entry, err)
+				}
+				var got []string
+				for _, c := range ambiguous.Candidates {
+					got = append(got, c.ID)
+				}
+				if len(got) != len(tt.candidates) {
+					t.Fatalf("got candidates %v, want %v", got, tt.candidates)
+				}
+				for i := range got {
+					if got[i] != tt.candidates[i] {
+						t.Fatalf("got candidates %v, want %v", got, tt.candidates)
+					}
+				}
+			default:
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				if entry.ID != tt.want {
+					t.Errorf("got %s, want %s", entry.ID, tt.want)
+				}
+			}
+		})
+	}
+}
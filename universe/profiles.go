@@ -0,0 +1,360 @@
+package universe
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/metrics"
+)
+
+const (
+	snippetBefore      = 60
+	snippetAfter       = 140
+	maxSnippetsPerHit  = 3
+	highlightStart     = "<em>"
+	highlightEnd       = "</em>"
+	minIndexedTermSize = 2
+)
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "to": true, "was": true, "which": true, "with": true,
+}
+
+// ProfileFilter struct narrows down the results of ProfileIndex's Search. Empty fields match everything.
+type ProfileFilter struct {
+	Sector   string
+	Category string
+	// Tags are all required to be present on a profile
+	Tags []string
+}
+
+// ProfileHit struct is an asset profile found by ProfileIndex's Search
+type ProfileHit struct {
+	ID       string    `json:"id"`
+	Symbol   string    `json:"symbol,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Slug     string    `json:"slug,omitempty"`
+	Sector   string    `json:"sector,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Score    float64   `json:"score"`
+	Snippets []Snippet `json:"snippets,omitempty"`
+}
+
+// Snippet struct is an excerpt of a profile's field with the matched terms wrapped in <em> tags
+type Snippet struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+type profileDoc struct {
+	hit    ProfileHit
+	fields []profileField
+}
+
+type profileField struct {
+	name string
+	text string
+}
+
+type posting struct {
+	doc       int
+	frequency int
+}
+
+// ProfileIndex struct is an in-process inverted index over the rich text of the profile of every asset
+// in the Snapshot
+type ProfileIndex struct {
+	mu        sync.RWMutex
+	docs      []profileDoc
+	postings  map[string][]posting
+	updatedAt time.Time
+}
+
+// NewProfileIndex func returns an empty ProfileIndex, which is filled by Update (ie. on each refresh of
+// the Snapshot)
+func NewProfileIndex() *ProfileIndex {
+	return &ProfileIndex{}
+}
+
+// Update func rebuilds the ProfileIndex from the profiles of assets
+func (p *ProfileIndex) Update(assets []messari.Asset) {
+	docs := make([]profileDoc, 0, len(assets))
+	postings := map[string][]posting{}
+	for _, asset := range assets {
+		doc := newProfileDoc(asset)
+		if len(doc.fields) == 0 {
+			continue
+		}
+		frequencies := map[string]int{}
+		for _, field := range doc.fields {
+			for _, term := range tokenize(field.text) {
+				frequencies[term]++
+			}
+		}
+		for term, frequency := range frequencies {
+			postings[term] = append(postings[term], posting{doc: len(docs), frequency: frequency})
+		}
+		docs = append(docs, doc)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.docs = docs
+	p.postings = postings
+	p.updatedAt = time.Now()
+}
+
+// UpdatedAt func returns when the ProfileIndex was last refreshed, zero if it never was
+func (p *ProfileIndex) UpdatedAt() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.updatedAt
+}
+
+// Search func returns up to limit profiles containing every term of q which pass filter, ranked
+// by tf-idf, along with the total number of matching profiles
+func (p *ProfileIndex) Search(q string, filter ProfileFilter, limit int) ([]ProfileHit, int) {
+	terms := uniqueStrings(tokenize(q))
+	if len(terms) == 0 {
+		return []ProfileHit{}, 0
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	metrics.CacheLookup("profiles", !p.updatedAt.IsZero())
+
+	scores := map[int]float64{}
+	for i, term := range terms {
+		termPostings := p.postings[term]
+		idf := math.Log(1 + float64(len(p.docs))/float64(1+len(termPostings)))
+		matched := make(map[int]float64, len(termPostings))
+		for _, post := range termPostings {
+			// every term has to be present, so only keep docs which matched the previous terms
+			if _, ok := scores[post.doc]; i > 0 && !ok {
+				continue
+			}
+			matched[post.doc] = scores[post.doc] + (1+math.Log(float64(post.frequency)))*idf
+		}
+		scores = matched
+	}
+
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		if filter.matches(p.docs[doc].hit) {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return p.docs[docs[i]].hit.Name < p.docs[docs[j]].hit.Name
+	})
+
+	total := len(docs)
+	if len(docs) > limit {
+		docs = docs[:limit]
+	}
+	hits := make([]ProfileHit, len(docs))
+	for i, doc := range docs {
+		hits[i] = p.docs[doc].hit
+		hits[i].Score = math.Round(scores[doc]*1000) / 1000
+		hits[i].Snippets = snippets(p.docs[doc].fields, terms)
+	}
+	return hits, total
+}
+
+func (f ProfileFilter) matches(hit ProfileHit) bool {
+	if f.Sector != "" && !strings.EqualFold(f.Sector, hit.Sector) {
+		return false
+	}
+	if f.Category != "" && !strings.EqualFold(f.Category, hit.Category) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		found := false
+		for _, t := range hit.Tags {
+			if strings.EqualFold(tag, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func newProfileDoc(asset messari.Asset) profileDoc {
+	profile := asset.Profile
+	overview := profile.General.Overview
+	doc := profileDoc{
+		hit: ProfileHit{
+			ID:       asset.ID,
+			Symbol:   asset.Symbol,
+			Name:     asset.Name,
+			Slug:     asset.Slug,
+			Sector:   deref(overview.Sector),
+			Category: deref(overview.Category),
+			Tags:     splitList(deref(overview.Tags)),
+		},
+	}
+	for _, field := range []profileField{
+		{"tagline", deref(overview.Tagline)},
+		{"project_details", deref(overview.ProjectDetails)},
+		{"background_details", deref(profile.General.Background.BackgroundDetails)},
+		{"regulatory_details", deref(profile.General.Regulation.RegulatoryDetails)},
+		{"sfar_summary", deref(profile.General.Regulation.SfarSummary)},
+		{"technology_details", deref(profile.Technology.Overview.TechnologyDetails)},
+		{"token_usage_details", deref(profile.Economics.Token.TokenUsageDetails)},
+		{"launch_details", deref(profile.Economics.Launch.General.LaunchDetails)},
+		{"supply_curve_details", deref(profile.Economics.ConsensusAndEmission.Supply.SupplyCurveDetails)},
+		{"consensus_details", deref(profile.Economics.ConsensusAndEmission.Consensus.ConsensusDetails)},
+		{"governance_details", deref(profile.Governance.GovernanceDetails)},
+	} {
+		field.text = plainText(field.text)
+		if field.text != "" {
+			doc.fields = append(doc.fields, field)
+		}
+	}
+	return doc
+}
+
+// snippets func returns an excerpt of up to maxSnippetsPerHit fields containing any of terms
+func snippets(fields []profileField, terms []string) []Snippet {
+	var out []Snippet
+	for _, field := range fields {
+		start, end, ok := firstMatch(field.text, terms)
+		if !ok {
+			continue
+		}
+		from := wordBoundary(field.text, start-snippetBefore, -1)
+		to := wordBoundary(field.text, end+snippetAfter, 1)
+		text := highlight(field.text[from:to], terms)
+		if from > 0 {
+			text = "…" + text
+		}
+		if to < len(field.text) {
+			text += "…"
+		}
+		out = append(out, Snippet{Field: field.name, Text: text})
+		if len(out) == maxSnippetsPerHit {
+			break
+		}
+	}
+	return out
+}
+
+// firstMatch func returns the byte offsets of the first word in text which is one of terms
+func firstMatch(text string, terms []string) (int, int, bool) {
+	for _, word := range words(text) {
+		if includes(terms, strings.ToLower(text[word[0]:word[1]])) {
+			return word[0], word[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// highlight func wraps every word in text which is one of terms in highlightStart and highlightEnd.
+// The rest of text is HTML escaped so the snippet can be rendered as is.
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	last := 0
+	for _, word := range words(text) {
+		if !includes(terms, strings.ToLower(text[word[0]:word[1]])) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:word[0]]))
+		b.WriteString(highlightStart)
+		b.WriteString(text[word[0]:word[1]])
+		b.WriteString(highlightEnd)
+		last = word[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+var wordPattern = regexp.MustCompile(`[\pL\pN]+`)
+
+func words(text string) [][]int {
+	return wordPattern.FindAllStringIndex(text, -1)
+}
+
+// wordBoundary func moves i in direction until it's at a space (or the start or end of text)
+// so snippets don't start or end in the middle of a word
+func wordBoundary(text string, i int, direction int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= len(text) {
+		return len(text)
+	}
+	for i > 0 && i < len(text) && text[i] != ' ' {
+		i += direction
+	}
+	return i
+}
+
+func tokenize(text string) []string {
+	var terms []string
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		if len(word) < minIndexedTermSize || stopWords[word] {
+			continue
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+func plainText(s string) string {
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, " "))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func uniqueStrings(v []string) []string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if !includes(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func includes(v []string, s string) bool {
+	for _, val := range v {
+		if val == s {
+			return true
+		}
+	}
+	return false
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
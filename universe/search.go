@@ -0,0 +1,171 @@
+package universe
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores given to an Entry depending on how the query matched it
+const (
+	scoreExactSymbol  = 100
+	scoreExactName    = 90
+	scorePrefixSymbol = 70
+	scorePrefixName   = 60
+	scorePrefixWord   = 50
+	scoreSubstring    = 40
+	scoreFuzzy        = 30
+	scoreTagline      = 20
+)
+
+// Match struct is an Entry found by Directory's Search
+type Match struct {
+	Entry
+	Score int `json:"score"`
+}
+
+// Search func returns up to limit entries matching q by symbol, name, slug or tagline. Matches
+// tolerate typos and partially typed words. They're ranked by their Score first, so a better match
+// always comes before a larger asset, and matches with the same Score by market cap, largest first.
+func (d *Directory) Search(q string, limit int) []Match {
+	q = normalize(q)
+	if q == "" || limit <= 0 {
+		return []Match{}
+	}
+
+	d.mu.RLock()
+	matches := make([]Match, 0, limit)
+	for _, entry := range d.entries {
+		if score := scoreEntry(q, entry); score > 0 {
+			matches = append(matches, Match{Entry: entry, Score: score})
+		}
+	}
+	d.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].MarketcapUsd > matches[j].MarketcapUsd
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func scoreEntry(q string, entry Entry) int {
+	symbol := normalize(entry.Symbol)
+	name := normalize(entry.Name)
+	slug := normalize(entry.Slug)
+
+	switch {
+	case symbol == q:
+		return scoreExactSymbol
+	case name == q || slug == q:
+		return scoreExactName
+	case strings.HasPrefix(symbol, q):
+		return scorePrefixSymbol
+	case strings.HasPrefix(name, q) || strings.HasPrefix(slug, q):
+		return scorePrefixName
+	}
+
+	words := strings.FieldsFunc(name+" "+slug, isSeparator)
+	for _, word := range words {
+		if strings.HasPrefix(word, q) {
+			return scorePrefixWord
+		}
+	}
+	if len(q) >= 3 && (strings.Contains(name, q) || strings.Contains(slug, q)) {
+		return scoreSubstring
+	}
+
+	if edits := maxEdits(q); edits > 0 {
+		best := edits + 1
+		for _, candidate := range append(words, symbol, name) {
+			if d := fuzzyDistance(q, candidate); d < best {
+				best = d
+			}
+		}
+		if best <= edits {
+			return scoreFuzzy - best*5
+		}
+	}
+
+	if len(q) >= 3 {
+		for _, word := range strings.FieldsFunc(normalize(entry.Tagline), isSeparator) {
+			if strings.HasPrefix(word, q) {
+				return scoreTagline
+			}
+		}
+	}
+	return 0
+}
+
+// maxEdits func returns how many typos are tolerated in q, short queries have to be exact
+func maxEdits(q string) int {
+	switch n := len([]rune(q)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// fuzzyDistance func returns the edit distance between q and candidate, or between q and the
+// start of candidate if it's longer so partially typed words still match
+func fuzzyDistance(q, candidate string) int {
+	qr, cr := []rune(q), []rune(candidate)
+	d := editDistance(qr, cr)
+	if len(cr) > len(qr) {
+		if p := editDistance(qr, cr[:len(qr)]); p < d {
+			d = p
+		}
+	}
+	return d
+}
+
+// editDistance func returns the number of insertions, deletions, substitutions and swaps of
+// adjacent characters needed to turn a into b
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func min(v int, vs ...int) int {
+	for _, o := range vs {
+		if o < v {
+			v = o
+		}
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+package universe
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "btc", b: "btc", want: 0},
+		{a: "", b: "eth", want: 3},
+		{a: "bitcoin", b: "bitcion", want: 1},
+		{a: "bitcoin", b: "bitcoins", want: 1},
+		{a: "bitcoin", b: "bitcon", want: 1},
+		{a: "bitcoin", b: "bitkoin", want: 1},
+		{a: "ethereum", b: "etheruem", want: 1},
+		{a: "chainlink", b: "chianlnik", want: 2},
+		{a: "kitten", b: "sitting", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+			if got := editDistance([]rune(tt.b), []rune(tt.a)); got != tt.want {
+				t.Errorf("got %d the other way around, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuzzyDistance(t *testing.T) {
+	// a partially typed word is as close as its start
+	if got := fuzzyDistance("ethe", "ethereum"); got != 0 {
+		t.Errorf("got %d for a prefix, want 0", got)
+	}
+	if got := fuzzyDistance("etha", "ethereum"); got != 1 {
+		t.Errorf("got %d for a prefix with a typo, want 1", got)
+	}
+}
+
+func TestScoreEntry(t *testing.T) {
+	entry := Entry{ID: "id", Symbol: "LINK", Name: "Chainlink", Slug: "chainlink", Tagline: "Decentralized oracle network"}
+	tests := []struct {
+		q    string
+		want int
+	}{
+		{q: "link", want: scoreExactSymbol},
+		{q: "chainlink", want: scoreExactName},
+		{q: "lin", want: scorePrefixSymbol},
+		{q: "chain", want: scorePrefixName},
+		{q: "ainli", want: scoreSubstring},
+		{q: "chianlink", want: scoreFuzzy - 5},
+		{q: "chianlnik", want: scoreFuzzy - 10},
+		{q: "orac", want: scoreTagline},
+		// short queries have to match exactly
+		{q: "lnk", want: 0},
+		{q: "ora", want: scoreTagline},
+		{q: "bitcoin", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			if got := scoreEntry(tt.q, entry); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	d := NewDirectory()
+	d.set([]Entry{
+		{ID: "id-eth-classic", Symbol: "ETC", Name: "Ethereum Classic", Slug: "ethereum-classic", MarketcapUsd: 50},
+		{ID: "id-ether-clone", Symbol: "ETHC", Name: "Ether Clone", Slug: "ether-clone", MarketcapUsd: 1},
+		{ID: "id-ethereum", Symbol: "ETH", Name: "Ethereum", Slug: "ethereum", MarketcapUsd: 500},
+		{ID: "id-eth-fork", Symbol: "ETH", Name: "Eth Fork", Slug: "eth-fork", MarketcapUsd: 5},
+		{ID: "id-bitcoin", Symbol: "BTC", Name: "Bitcoin", Slug: "bitcoin", MarketcapUsd: 1000},
+	})
+
+	tests := []struct {
+		q     string
+		limit int
+		want  []string
+	}{
+		// exact symbols first, then by market cap among the same score
+		{q: "eth", limit: 10, want: []string{"id-ethereum", "id-eth-fork", "id-ether-clone", "id-eth-classic"}},
+		{q: "eth", limit: 2, want: []string{"id-ethereum", "id-eth-fork"}},
+		// a better match wins over a larger market cap
+		{q: "ethereum", limit: 10, want: []string{"id-ethereum", "id-eth-classic"}},
+		{q: "etherium", limit: 10, want: []string{"id-ethereum", "id-eth-classic"}},
+		{q: " BTC ", limit: 10, want: []string{"id-bitcoin"}},
+		{q: "doge", limit: 10, want: []string{}},
+		{q: "", limit: 10, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			matches := d.Search(tt.q, tt.limit)
+			got := make([]string, len(matches))
+			for i, m := range matches {
+				got[i] = m.ID
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
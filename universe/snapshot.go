@@ -0,0 +1,131 @@
+package universe
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/metrics"
+)
+
+// volumeBaselineWindow is roughly how far back the rolling baseline of each asset's volume looks
+const volumeBaselineWindow = 7 * 24 * time.Hour
+
+// Snapshot struct is an in-memory copy of every asset which has metrics and a profile, along
+// with its metrics and its whole profile so any of their fields can be filtered on
+type Snapshot struct {
+	client *messari.Client
+
+	mu        sync.RWMutex
+	assets    []messari.Asset
+	updatedAt time.Time
+	// volumeBaselines is an exponentially weighted moving average of each asset's 24 hour volume by ID,
+	// as of the refresh before the latest one so the latest volume can be compared against it
+	volumeBaselines map[string]float64
+	nextBaselines   map[string]float64
+
+	listenersMu sync.Mutex
+	listeners   []func(assets []messari.Asset)
+}
+
+// NewSnapshot func returns an empty Snapshot which is filled using m
+func NewSnapshot(m *messari.Client) *Snapshot {
+	return &Snapshot{client: m}
+}
+
+// Run func refreshes the Snapshot right away and then every interval until ctx is done. interval is called
+// after each refresh and whenever changed is signaled, so it can change while running (ie. when the config
+// is reloaded).
+func (s *Snapshot) Run(ctx context.Context, interval func() time.Duration, changed <-chan struct{}) {
+	runEvery(ctx, interval, changed, "asset snapshot", "snapshot", s.Refresh)
+}
+
+// Refresh func replaces the Snapshot's assets with the latest ones from Messari
+func (s *Snapshot) Refresh(ctx context.Context) error {
+	assets, err := fetchAllAssets(ctx, s.client, &messari.GetAllAssetsOptions{
+		Fields: messari.MustFields(
+			messari.FieldID,
+			messari.FieldName,
+			messari.FieldSymbol,
+			messari.FieldSlug,
+			messari.FieldMetrics,
+			messari.FieldProfile,
+		),
+		WithMertricsOnly: boolPtr(true),
+		WithProfilesOnly: boolPtr(true),
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(assets, func(i, j int) bool {
+		return assets[i].Metrics.Marketcap.CurrentMarketcapUsd > assets[j].Metrics.Marketcap.CurrentMarketcapUsd
+	})
+
+	s.mu.Lock()
+	now := time.Now()
+	s.volumeBaselines = s.nextBaselines
+	s.nextBaselines = updateBaselines(s.nextBaselines, assets, now.Sub(s.updatedAt))
+	s.assets = assets
+	s.updatedAt = now
+	s.mu.Unlock()
+
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	for _, fn := range s.listeners {
+		fn(assets)
+	}
+	return nil
+}
+
+// OnRefresh func registers fn to be called with the Snapshot's assets after each refresh, largest market
+// cap first. The slice is shared and must not be modified.
+func (s *Snapshot) OnRefresh(fn func(assets []messari.Asset)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// VolumeBaseline func returns the rolling baseline of the 24 hour volume of the asset with the given ID,
+// false if there isn't one yet (ie. the Snapshot has only been refreshed once)
+func (s *Snapshot) VolumeBaseline(id string) (float64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.volumeBaselines[id]
+	return v, ok
+}
+
+// updateBaselines func returns the moving averages of prev updated with the volume of assets, with each
+// asset's latest volume weighted by how long it's been since the last update
+func updateBaselines(prev map[string]float64, assets []messari.Asset, elapsed time.Duration) map[string]float64 {
+	alpha := 1 - math.Exp(-float64(elapsed)/float64(volumeBaselineWindow))
+	next := make(map[string]float64, len(assets))
+	for _, asset := range assets {
+		volume := asset.Metrics.MarketData.VolumeLast24Hours
+		if baseline, ok := prev[asset.ID]; ok {
+			next[asset.ID] = baseline + alpha*(volume-baseline)
+			continue
+		}
+		next[asset.ID] = volume
+	}
+	return next
+}
+
+// Assets func returns every asset in the Snapshot, largest market cap first, and when they were
+// fetched. The zero time is returned if the Snapshot hasn't been filled yet. The returned slice
+// is shared and must not be modified.
+func (s *Snapshot) Assets() ([]messari.Asset, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	metrics.CacheLookup("snapshot", !s.updatedAt.IsZero())
+	return s.assets, s.updatedAt
+}
+
+// UpdatedAt func returns when the Snapshot was last refreshed, zero if it never was
+func (s *Snapshot) UpdatedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
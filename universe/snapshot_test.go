@@ -0,0 +1,57 @@
+package universe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ultd/messari-server/messari"
+)
+
+func TestSnapshotUpdatesDirectoryAndProfiles(t *testing.T) {
+	var pages int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if r.URL.Query().Get("page") != "1" {
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		w.Write([]byte(`{"data": [
+			{"id": "id-aave", "symbol": "AAVE", "name": "Aave", "slug": "aave",
+				"metrics": {"marketcap": {"current_marketcap_usd": 100}},
+				"profile": {"general": {"overview": {"tagline": "Lending protocol", "project_details": "Aave lets anyone lend."}}}},
+			{"id": "id-bitcoin", "symbol": "BTC", "name": "Bitcoin", "slug": "bitcoin",
+				"metrics": {"marketcap": {"current_marketcap_usd": 1000}},
+				"profile": {"general": {"overview": {"project_details": "Peer to peer electronic cash."}}}}
+		]}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	snapshot := NewSnapshot(messari.New("test-api-key", messari.WithBaseURL(u), messari.WithRateLimit(6000, 100)))
+	dir := NewDirectory()
+	profiles := NewProfileIndex()
+	snapshot.OnRefresh(dir.Update)
+	snapshot.OnRefresh(profiles.Update)
+	if err := snapshot.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if pages != 2 {
+		t.Errorf("got %d requests, want the snapshot's 2 pages only", pages)
+	}
+	if dir.UpdatedAt().IsZero() || profiles.UpdatedAt().IsZero() {
+		t.Fatal("directory or profile index wasn't updated")
+	}
+	if entry, err := dir.Resolve("aave"); err != nil || entry.ID != "id-aave" || entry.Tagline != "Lending protocol" {
+		t.Errorf("got %+v and %v resolving aave, want its entry", entry, err)
+	}
+	if matches := dir.Search("bit", 10); len(matches) != 1 || matches[0].ID != "id-bitcoin" {
+		t.Errorf("got %+v searching bit, want bitcoin", matches)
+	}
+	if hits, total := profiles.Search("lend", ProfileFilter{}, 10); total != 1 || hits[0].ID != "id-aave" {
+		t.Errorf("got %+v (%d) searching profiles for lend, want aave", hits, total)
+	}
+}
@@ -0,0 +1,82 @@
+// Package universe keeps a local, periodically refreshed copy of Messari's asset universe (the Snapshot)
+// along with the Directory and ProfileIndex built from it, so routes can answer without paging through
+// the whole API on every request.
+package universe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/ultd/messari-server/messari"
+	"github.com/ultd/messari-server/metrics"
+)
+
+// fetchAllAssets func pages through GetAllAssets until Messari runs out of assets
+func fetchAllAssets(ctx context.Context, m *messari.Client, options *messari.GetAllAssetsOptions) ([]messari.Asset, error) {
+	opts := *options
+	opts.Limit = intPtr(500)
+
+	assets := make([]messari.Asset, 0, 500)
+	for page := 1; ; page++ {
+		opts.Page = intPtr(page)
+		resp, err := m.GetAllAssets(ctx, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not get page %d of assets: %w", page, err)
+		}
+		if len(resp.Data) == 0 {
+			return assets, nil
+		}
+		assets = append(assets, resp.Data...)
+	}
+}
+
+// runEvery func calls refresh right away and then every interval() until ctx is done,
+// logging any errors. cache is the name refreshes are recorded under in metrics. interval is
+// called again whenever changed is signaled, so a new interval applies without waiting for the
+// current one to be over.
+func runEvery(ctx context.Context, interval func() time.Duration, changed <-chan struct{}, name string, cache string, refresh func(context.Context) error) {
+	for {
+		start := time.Now()
+		err := refresh(ctx)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		metrics.ObserveRefresh(cache, time.Since(start), err)
+		if err != nil {
+			logrus.Errorf("could not refresh %s: %v", name, err)
+		} else {
+			logrus.Debugf("refreshed %s in %s", name, time.Since(start))
+		}
+		if !wait(ctx, start, interval, changed) {
+			return
+		}
+	}
+}
+
+// wait func waits until interval() after start, returning false if ctx is done first. The wait is
+// measured from the start of the refresh, like a ticker would, even when interval changes.
+func wait(ctx context.Context, start time.Time, interval func() time.Duration, changed <-chan struct{}) bool {
+	timer := time.NewTimer(time.Until(start.Add(interval())))
+	defer func() { timer.Stop() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+			timer.Stop()
+			timer = time.NewTimer(time.Until(start.Add(interval())))
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
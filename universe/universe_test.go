@@ -0,0 +1,47 @@
+package universe
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunEveryAppliesNewInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var interval int64 = int64(time.Hour)
+	changed := make(chan struct{}, 1)
+	refreshed := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runEvery(ctx, func() time.Duration { return time.Duration(atomic.LoadInt64(&interval)) }, changed, "test", "test", func(context.Context) error {
+			refreshed <- struct{}{}
+			return nil
+		})
+	}()
+
+	<-refreshed
+	select {
+	case <-refreshed:
+		t.Fatal("refreshed again before the interval was over")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the shorter interval is already over since the last refresh, so it refreshes right away
+	atomic.StoreInt64(&interval, int64(10*time.Millisecond))
+	changed <- struct{}{}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("didn't refresh after the interval was shortened")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("didn't stop once ctx was done")
+	}
+}